@@ -17,76 +17,255 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
-	"sync"
+	"runtime"
 
 	"github.com/biogo/biogo/align"
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/seq"
 	"github.com/biogo/biogo/seq/linear"
+	"golang.org/x/sync/errgroup"
 
+	"github.com/mys721tx/gsearch/pkg/pairwise"
+	"github.com/mys721tx/gsearch/pkg/pool"
+	"github.com/mys721tx/gsearch/pkg/relabel"
 	"github.com/mys721tx/gsearch/pkg/seqio"
 )
 
 var (
 	ref      = flag.String("reference", "", "path to the reference sequence fasta file")
 	tgt      = flag.String("target", "", "path to the target sequence fasta file")
+	refFmt   = flag.String("reference_format", "auto", "format of the reference file, auto, fasta or fastq")
+	tgtFmt   = flag.String("target_format", "auto", "format of the target file, auto, fasta or fastq")
+	phred    = flag.Int("phred", 33, "Phred offset of the FASTQ quality, 33 or 64")
 	match    = flag.Int("match", 2, "score for match")
 	mismatch = flag.Int("mismatch", -1, "score for mismatch")
 	gap      = flag.Int("gap", -2, "score for gap")
-	gapopen  = flag.Int("gap_open", 0, "score for gap open")
-	wg       sync.WaitGroup
+	pmatrix  = flag.String("matrix", "", "name of a built-in substitution matrix or path to an NCBI matrix file, replacing -match and -mismatch, default to BLOSUM62 for proteins")
+	palpha   = flag.String("alphabet", "dna", "alphabet of the sequences, auto, dna, rna or protein")
+	gapopen  = flag.Int("gap_open", 0, "score for gap open of affine gaps")
+	gapModel = flag.String("gap_model", "affine", "gap model, linear or affine")
+	pmode    = flag.String("mode", "global", "alignment mode, global, local, fitted or ends-free")
+	prelabel = flag.String("relabel", "", "prefix to relabel targets with a counter")
+	sha1     = flag.Bool("relabel_sha1", false, "relabel targets with the SHA1 digest of their letters")
+	md5      = flag.Bool("relabel_md5", false, "relabel targets with the MD5 digest of their letters")
+	keep     = flag.Bool("relabel_keep", false, "keep the original label as the label attribute")
+	threads  = flag.Int("threads", runtime.NumCPU(), "number of targets aligned in parallel")
+	outFmt   = flag.String("outfmt", "text", "output format, text, sam, blast6 or jsonl")
+	iddef    = flag.Int("iddef", int(pairwise.Internal), "identity definition of vsearch, from 0 to 4")
 )
 
-func makeScoreMatrix() *align.Linear {
-	m := align.Linear{
-		{0, *gap, *gap, *gap, *gap},
-		{*gap, *match, *mismatch, *mismatch, *mismatch},
-		{*gap, *mismatch, *match, *mismatch, *mismatch},
-		{*gap, *mismatch, *mismatch, *match, *mismatch},
-		{*gap, *mismatch, *mismatch, *mismatch, *match},
+// scoreMatrix returns the matrix of -matrix over the alphabet of a molecule. If
+// -matrix is empty, proteins are scored by BLOSUM62 and nucleotides by the
+// IUPAC matrix of -match and -mismatch.
+func scoreMatrix(mol seqio.Molecule) (pairwise.Matrix, error) {
+	var (
+		m   pairwise.Matrix
+		err error
+	)
+
+	switch {
+	case *pmatrix != "":
+		m, err = pairwise.LoadMatrix(*pmatrix)
+	case mol == seqio.Protein:
+		m = pairwise.Matrices["BLOSUM62"]
+	default:
+		m = pairwise.IUPAC(*match, *mismatch)
+	}
+
+	if err != nil {
+		return m, err
 	}
-	return &m
+
+	return m.Of(mol.Alphabet().Moltype())
 }
 
-func alignSW(ref *linear.Seq, score align.NWAffine, seqs <-chan *linear.Seq) {
-	defer wg.Done()
+// result is the alignment of a target to the reference.
+type result struct {
+	tgt   *linear.Seq
+	aln   []feat.Pair
+	fa    [2]alphabet.Slice
+	stats *pairwise.Alignment
+}
 
-	for tgt := range seqs {
-		aln, err := score.Align(ref, tgt)
+// alignTargets aligns the targets to the reference and writes the alignments
+// by w, or as the pairs of biogo and the formatted alignment if w is nil.
+func alignTargets(
+	ctx context.Context, ref *linear.Seq, score align.Aligner,
+	rl *relabel.Relabeler, threads int, w *pairwise.Writer,
+	seqs <-chan seq.Sequence,
+) error {
+	return pool.Ordered(
+		ctx, threads, seqs,
+		func(s seq.Sequence) (interface{}, error) {
+			tgt := seqio.AsSeq(s)
+			tgt.Alpha = ref.Alpha
 
-		if err != nil {
-			log.Fatalf("failed to align: %v", err)
-		}
+			if err := pairwise.CheckLetters(tgt.Alpha, tgt.Seq); err != nil {
+				return nil, fmt.Errorf("failed to align %q: %w", tgt.ID, err)
+			}
 
-		fmt.Printf("%s\n", aln)
-		fa := align.Format(ref, tgt, aln, '-')
-		fmt.Printf("%s\n%s\n", fa[0], fa[1])
-	}
+			aln, err := score.Align(ref, tgt)
+
+			if err != nil {
+				return nil, fmt.Errorf("failed to align %q: %w", tgt.ID, err)
+			}
+
+			r := &result{tgt: tgt, aln: aln}
+
+			if w == nil {
+				r.fa = align.Format(ref, tgt, aln, '-')
+			} else {
+				r.stats = pairwise.NewAlignment(ref.Alpha, ref.Seq, tgt.Seq, aln)
+			}
+
+			return r, nil
+		},
+		func(v interface{}) error {
+			// Targets are relabeled in the order of the input.
+			r := v.(*result)
+			rl.Seq(r.tgt)
+
+			if w != nil {
+				return w.Write(ref, r.tgt, r.stats)
+			}
+
+			fmt.Printf("%s\n", r.aln)
+			fmt.Printf("%s\n%s\n", r.fa[0], r.fa[1])
+
+			return nil
+		},
+	)
 }
 
 func main() {
 	flag.Parse()
 
-	nw := align.NWAffine{
-		Matrix:  *makeScoreMatrix(),
-		GapOpen: *gapopen,
+	fmtRef, err := seqio.ParseFormat(*refFmt)
+
+	if err != nil {
+		log.Fatalf("failed to parse reference format: %s", err)
+	}
+
+	fmtTgt, err := seqio.ParseFormat(*tgtFmt)
+
+	if err != nil {
+		log.Fatalf("failed to parse target format: %s", err)
+	}
+
+	enc, err := seqio.ParseEncoding(*phred)
+
+	if err != nil {
+		log.Fatalf("failed to parse quality encoding: %s", err)
+	}
+
+	rl, err := relabel.New(relabel.Options{
+		Prefix: *prelabel,
+		SHA1:   *sha1,
+		MD5:    *md5,
+		Keep:   *keep,
+	})
+
+	if err != nil {
+		log.Fatalf("failed to relabel: %s", err)
+	}
+
+	mode, err := pairwise.ParseMode(*pmode)
+
+	if err != nil {
+		log.Fatalf("failed to parse mode: %s", err)
+	}
+
+	var affine bool
+
+	switch *gapModel {
+	case "linear":
+	case "affine":
+		affine = true
+	default:
+		log.Fatalf("failed to parse gap model: unknown gap model %q", *gapModel)
+	}
+
+	mol, err := seqio.ParseMolecule(*palpha)
+
+	if err != nil {
+		log.Fatalf("failed to parse alphabet: %s", err)
 	}
 
 	if fRef, err := os.Open(*ref); err != nil {
 		log.Fatalf("failed to open %q: %s", *ref, err)
-	} else if sRef, err := seqio.ReadSeq(fRef); err != nil {
+	} else if inRef, err := seqio.Open(fRef, fmtRef, mol, enc); err != nil {
+		log.Fatalf("failed to open %q: %s", *ref, err)
+	} else if sRef, err := inRef.Read(); err != nil {
 		log.Fatalf("failed to read reference sequence %q: %s", *ref, err)
 	} else if fTgt, err := os.Open(*tgt); err != nil {
 		log.Fatalf("failed to open %q: %s", *tgt, err)
+	} else if inTgt, err := seqio.Open(fTgt, fmtTgt, inRef.Molecule, enc); err != nil {
+		log.Fatalf("failed to open %q: %s", *tgt, err)
 	} else {
-		csTgt := make(chan *linear.Seq)
+		// The targets are read as the molecule of the reference.
+		m, err := scoreMatrix(inRef.Molecule)
 
-		wg.Add(2)
-		go seqio.ScanSeq(fTgt, csTgt, &wg)
-		go alignSW(sRef, nw, csTgt)
+		if err != nil {
+			log.Fatalf("failed to load matrix: %s", err)
+		}
+
+		aligner, err := pairwise.ModeAligner(mode, m.WithGap(*gap), *gapopen, affine)
+
+		if err != nil {
+			log.Fatalf("failed to align: %s", err)
+		}
+
+		// Qualities are dropped so FASTA and FASTQ can be aligned together.
+		lRef := seqio.AsSeq(sRef)
+		lRef.Alpha = m.Alphabet
+
+		if err := pairwise.CheckLetters(lRef.Alpha, lRef.Seq); err != nil {
+			log.Fatalf("failed to read reference sequence %q: %s", *ref, err)
+		}
 
-		wg.Wait()
+		var w *pairwise.Writer
+		out := bufio.NewWriter(os.Stdout)
+
+		if *outFmt != "text" {
+			f, err := pairwise.ParseFormat(*outFmt)
+
+			if err != nil {
+				log.Fatalf("failed to parse output format: %s", err)
+			}
+
+			def, err := pairwise.ParseIDDef(*iddef)
+
+			if err != nil {
+				log.Fatalf("failed to parse identity definition: %s", err)
+			}
+
+			w = pairwise.NewWriter(out, f, def)
+
+			if err := w.WriteHeader(lRef); err != nil {
+				log.Fatalf("failed to write header: %s", err)
+			}
+		}
+
+		csTgt := make(chan seq.Sequence)
+
+		g, ctx := errgroup.WithContext(context.Background())
+
+		g.Go(func() error { return seqio.ScanContext(ctx, inTgt, csTgt) })
+		g.Go(func() error { return alignTargets(ctx, lRef, aligner, rl, *threads, w, csTgt) })
+
+		if err := g.Wait(); err != nil {
+			log.Fatalf("failed to align %q: %s", *tgt, err)
+		}
+
+		if err := out.Flush(); err != nil {
+			log.Fatalf("failed to write alignments: %s", err)
+		}
 	}
 }
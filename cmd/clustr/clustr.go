@@ -18,18 +18,22 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"flag"
 	"io"
 	"log"
 	"os"
-	"sort"
-	"sync"
 
-	"github.com/biogo/biogo/io/seqio/fasta"
-	"github.com/biogo/biogo/seq/linear"
+	"github.com/biogo/biogo/seq"
+	"golang.org/x/sync/errgroup"
 
+	"github.com/mys721tx/gsearch/pkg/centroid"
 	"github.com/mys721tx/gsearch/pkg/cluster"
+	"github.com/mys721tx/gsearch/pkg/extsort"
+	"github.com/mys721tx/gsearch/pkg/kmer"
+	"github.com/mys721tx/gsearch/pkg/relabel"
 	"github.com/mys721tx/gsearch/pkg/seqio"
+	"github.com/mys721tx/gsearch/pkg/uc"
 )
 
 var (
@@ -41,7 +45,7 @@ var (
 	pout = flag.String(
 		"out",
 		"",
-		"path to the output FASTA file, default to stdout.",
+		"path to the output FASTA file, compressed by its extension, default to stdout.",
 	)
 	min = flag.Int(
 		"min",
@@ -53,12 +57,120 @@ var (
 		cluster.MaxLen,
 		"maximal abundance of a sequence, default to 0.",
 	)
-	wg sync.WaitGroup
+	puc = flag.String(
+		"uc",
+		"",
+		"path to the output UC file, compressed by its extension, default to none.",
+	)
+	pfmt = flag.String(
+		"format",
+		"auto",
+		"format of the input file, auto, fasta or fastq, default to auto.",
+	)
+	phred = flag.Int(
+		"phred",
+		33,
+		"Phred offset of the FASTQ quality, 33 or 64, default to 33.",
+	)
+	palpha = flag.String(
+		"alphabet",
+		"dna",
+		"alphabet of the input file, auto, dna, rna or protein, default to dna.",
+	)
+	level = flag.Int(
+		"compress-level",
+		seqio.DefaultLevel,
+		"compression level of the output file, default to 0 for the default level.",
+	)
+	id = flag.Float64(
+		"id",
+		centroid.DefaultIdentity,
+		"minimal identity to join a centroid, from 0 to 1, default to 0.97.",
+	)
+	accepts = flag.Int(
+		"maxaccepts",
+		kmer.DefaultMaxAccepts,
+		"centroids above -id to accept before stopping the search, default to 1, 0 for unlimited.",
+	)
+	rejects = flag.Int(
+		"maxrejects",
+		kmer.DefaultMaxRejects,
+		"centroids below -id to reject before stopping the search, default to 32, 0 for unlimited.",
+	)
+	pmem = flag.String(
+		"max-memory",
+		"0",
+		"memory of sequences before spilling to disk, such as 8G, default to 0 for unlimited.",
+	)
+	ptmp = flag.String(
+		"temp-dir",
+		"",
+		"directory of the spilled sequences, default to the temporary directory.",
+	)
+	prelabel = flag.String(
+		"relabel",
+		"",
+		"prefix to relabel sequences with a counter, default to no relabeling.",
+	)
+	sha1 = flag.Bool(
+		"relabel-sha1",
+		false,
+		"relabel sequences with the SHA1 digest of their letters, default to false.",
+	)
+	md5 = flag.Bool(
+		"relabel-md5",
+		false,
+		"relabel sequences with the MD5 digest of their letters, default to false.",
+	)
+	keep = flag.Bool(
+		"relabel-keep",
+		false,
+		"keep the original label as the label attribute, default to false.",
+	)
 )
 
 func main() {
 	flag.Parse()
 
+	maxMem, err := extsort.ParseSize(*pmem)
+
+	if err != nil {
+		log.Panicf("failed to parse memory: %v", err)
+	}
+
+	if *puc != "" && maxMem > 0 {
+		log.Panicf("failed to write %q: -uc cannot be used with -max-memory", *puc)
+	}
+
+	rl, err := relabel.New(relabel.Options{
+		Prefix: *prelabel,
+		SHA1:   *sha1,
+		MD5:    *md5,
+		Keep:   *keep,
+	})
+
+	if err != nil {
+		log.Panicf("failed to relabel: %v", err)
+	}
+
+	format, err := seqio.ParseFormat(*pfmt)
+
+	if err != nil {
+		log.Panicf("failed to parse format: %v", err)
+	}
+
+	enc, err := seqio.ParseEncoding(*phred)
+
+	if err != nil {
+		log.Panicf("failed to parse quality encoding: %v", err)
+	}
+
+	mol, err := seqio.ParseMolecule(*palpha)
+
+	if err != nil {
+		log.Panicf("failed to parse alphabet: %v", err)
+	}
+
 	var fin, fout *os.File
 
 	if *pin == "" {
@@ -83,7 +195,42 @@ func main() {
 		}
 	}()
 
-	w := bufio.NewWriter(fout)
+	in, err := seqio.Open(fin, format, mol, enc)
+
+	if err != nil {
+		log.Panicf("failed to open %q: %v", *pin, err)
+	}
+
+	defer func() {
+		if err := in.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", *pin, err)
+		}
+	}()
+
+	cl, err := centroid.New(centroid.Options{
+		Identity:   *id,
+		Moltype:    in.Molecule.Alphabet().Moltype(),
+		MaxAccepts: *accepts,
+		MaxRejects: *rejects,
+	})
+
+	if err != nil {
+		log.Panicf("failed to cluster: %v", err)
+	}
+
+	z, err := seqio.NewCompressor(fout, seqio.CompressionByExt(*pout), *level)
+
+	if err != nil {
+		log.Panicf("failed to compress %q: %v", *pout, err)
+	}
+
+	defer func() {
+		if err := z.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", *pout, err)
+		}
+	}()
+
+	w := bufio.NewWriter(z)
 
 	defer func() {
 		if err := w.Flush(); err != nil {
@@ -91,35 +238,120 @@ func main() {
 		}
 	}()
 
-	ch := make(chan *linear.Seq)
+	var ucw *uc.Writer
+
+	if *puc != "" {
+		f, err := os.Create(*puc)
 
-	wg.Add(1)
-	go seqio.ScanSeq(fin, ch, &wg) // TODO: handling panic
+		if err != nil {
+			log.Panicf("failed to open %q: %v", *puc, err)
+		}
+
+		defer func() {
+			if err := f.Close(); err != nil {
+				log.Panicf("failed to close %q: %v", *puc, err)
+			}
+		}()
 
-	l := func() []*cluster.Cluster {
+		z, err := seqio.NewCompressor(f, seqio.CompressionByExt(*puc), *level)
 
-		var l []*cluster.Cluster
-		for s := range ch {
-			c := cluster.ParseAnno(s)
-			l = append(l, c)
+		if err != nil {
+			log.Panicf("failed to compress %q: %v", *puc, err)
 		}
 
-		sort.Sort(cluster.ByAbundance(l))
+		defer func() {
+			if err := z.Close(); err != nil {
+				log.Panicf("failed to close %q: %v", *puc, err)
+			}
+		}()
+
+		b := bufio.NewWriter(z)
+
+		defer func() {
+			if err := b.Flush(); err != nil {
+				log.Panicf("failed to flush %q: %v", *puc, err)
+			}
+		}()
+
+		ucw = uc.NewWriter(b)
+	}
 
-		return l
+	ch := make(chan seq.Sequence)
+
+	g, ctx := errgroup.WithContext(context.Background())
+
+	g.Go(func() error {
+		return seqio.ScanContext(ctx, in, ch)
+	})
+
+	sorter := extsort.NewSorter(extsort.ByAbundance, maxMem, *ptmp)
+
+	defer func() {
+		if err := sorter.Close(); err != nil {
+			log.Panicf("failed to remove spilled sequences: %v", err)
+		}
 	}()
 
-	wg.Wait()
+	g.Go(func() error {
+		for i := int64(0); ; i++ {
+			select {
+			case s, ok := <-ch:
+				if !ok {
+					return nil
+				}
+
+				r := extsort.Record{Order: i, Cluster: cluster.ParseAnno(s)}
+
+				if err := sorter.Add(&r); err != nil {
+					return err
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+
+	if err := g.Wait(); err != nil {
+		log.Panicf("failed to read %q: %v", *pin, err)
+	}
+
+	it, err := sorter.Sort()
+
+	if err != nil {
+		log.Panicf("failed to sort %q: %v", *pin, err)
+	}
+
+	for {
+		r, err := it.Next()
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			log.Panicf("failed to sort %q: %v", *pin, err)
+		}
 
-	func(f io.Writer, min, max int) {
-		w := fasta.NewWriter(f, seqio.WidthCol)
+		if _, err := cl.Add(r.Cluster); err != nil {
+			log.Panicf("failed to cluster %q: %v", *pin, err)
+		}
+	}
 
-		for _, c := range l {
+	func(w seqio.Writer, ucw *uc.Writer, min, max int) {
+		for _, c := range cl.Centroids() {
 			if c.PassFilter(min, max) {
+				rl.Cluster(c)
+
 				if _, err := w.Write(c); err != nil {
 					log.Panicf("Error occurred during write: %s", err)
 				}
+
+				if ucw == nil {
+					continue
+				}
+
+				if err := ucw.Write(c); err != nil {
+					log.Panicf("failed to write %q: %v", *puc, err)
+				}
 			}
 		}
-	}(w, *min, *max)
+	}(seqio.NewWriter(w, in.Format), ucw, *min, *max)
 }
@@ -18,22 +18,29 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"flag"
 	"log"
 	"os"
-	"sync"
+	"runtime"
 
-	"github.com/biogo/biogo/seq/linear"
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/mys721tx/gsearch/pkg/cluster"
 	"github.com/mys721tx/gsearch/pkg/derep"
+	"github.com/mys721tx/gsearch/pkg/extsort"
+	"github.com/mys721tx/gsearch/pkg/relabel"
 	"github.com/mys721tx/gsearch/pkg/seqio"
+	"github.com/mys721tx/gsearch/pkg/uc"
 )
 
 var (
-	pin, pout string
-	max, min  int
-	wg        sync.WaitGroup
+	pin, pout, pfmt, palpha, merge, pstrand, pmode string
+	pmem, ptmp, porder, prelabel, puc              string
+	max, min, phred, level, threads                int
+	low, drop, sha1, md5, keep                     bool
 )
 
 func main() {
@@ -63,10 +70,195 @@ func main() {
 		&pout,
 		"out",
 		"",
-		"path to the output FASTA file, default to stdout.",
+		"path to the output FASTA file, compressed by its extension, default to stdout.",
+	)
+
+	flag.StringVar(
+		&puc,
+		"uc",
+		"",
+		"path to the output UC file, compressed by its extension, default to none.",
+	)
+
+	flag.StringVar(
+		&pfmt,
+		"format",
+		"auto",
+		"format of the input file, auto, fasta or fastq, default to auto.",
+	)
+
+	flag.StringVar(
+		&palpha,
+		"alphabet",
+		"dna",
+		"alphabet of the input file, auto, dna, rna or protein, default to dna.",
+	)
+
+	flag.IntVar(
+		&phred,
+		"phred",
+		33,
+		"Phred offset of the FASTQ quality, 33 or 64, default to 33.",
+	)
+
+	flag.IntVar(
+		&level,
+		"compress-level",
+		seqio.DefaultLevel,
+		"compression level of the output file, default to 0 for the default level.",
+	)
+
+	flag.StringVar(
+		&merge,
+		"merge",
+		"",
+		"comma separated key=policy to merge header attributes, default to keep-first.",
+	)
+
+	flag.StringVar(
+		&pstrand,
+		"strand",
+		"plus",
+		"strand to compare, plus or both, default to plus.",
+	)
+
+	flag.StringVar(
+		&pmode,
+		"mode",
+		"full",
+		"mode to match sequences, full or prefix, default to full.",
+	)
+
+	flag.StringVar(
+		&porder,
+		"sort",
+		"abundance",
+		"order of the output, abundance, input, length or sequence, default to abundance.",
+	)
+
+	flag.StringVar(
+		&prelabel,
+		"relabel",
+		"",
+		"prefix to relabel sequences with a counter, default to no relabeling.",
+	)
+
+	flag.BoolVar(
+		&sha1,
+		"relabel-sha1",
+		false,
+		"relabel sequences with the SHA1 digest of their letters, default to false.",
+	)
+
+	flag.BoolVar(
+		&md5,
+		"relabel-md5",
+		false,
+		"relabel sequences with the MD5 digest of their letters, default to false.",
+	)
+
+	flag.BoolVar(
+		&keep,
+		"relabel-keep",
+		false,
+		"keep the original label as the label attribute, default to false.",
+	)
+
+	flag.BoolVar(
+		&low,
+		"low-memory",
+		false,
+		"key sequences by hash and store them in 2 bits, default to false.",
+	)
+
+	flag.BoolVar(
+		&drop,
+		"drop-merged",
+		false,
+		"discard the headers of merged sequences, default to false.",
+	)
+
+	flag.StringVar(
+		&pmem,
+		"max-memory",
+		"0",
+		"memory of unique sequences before spilling to disk, such as 8G, default to 0 for unlimited.",
+	)
+
+	flag.IntVar(
+		&threads,
+		"threads",
+		runtime.NumCPU(),
+		"number of threads to dereplicate, default to the number of CPUs.",
+	)
+
+	flag.StringVar(
+		&ptmp,
+		"temp-dir",
+		"",
+		"directory of the spilled sequences, default to the temporary directory.",
 	)
 	flag.Parse()
 
+	maxMem, err := extsort.ParseSize(pmem)
+
+	if err != nil {
+		log.Panicf("failed to parse memory: %v", err)
+	}
+
+	rl, err := relabel.New(relabel.Options{
+		Prefix: prelabel,
+		SHA1:   sha1,
+		MD5:    md5,
+		Keep:   keep,
+	})
+
+	if err != nil {
+		log.Panicf("failed to relabel: %v", err)
+	}
+
+	order, err := derep.ParseOrder(porder)
+
+	if err != nil {
+		log.Panicf("failed to parse order: %v", err)
+	}
+
+	mode, err := derep.ParseMode(pmode)
+
+	if err != nil {
+		log.Panicf("failed to parse mode: %v", err)
+	}
+
+	strand, err := derep.ParseStrand(pstrand)
+
+	if err != nil {
+		log.Panicf("failed to parse strand: %v", err)
+	}
+
+	policies, err := cluster.ParsePolicies(merge)
+
+	if err != nil {
+		log.Panicf("failed to parse merge policies: %v", err)
+	}
+
+	format, err := seqio.ParseFormat(pfmt)
+
+	if err != nil {
+		log.Panicf("failed to parse format: %v", err)
+	}
+
+	mol, err := seqio.ParseMolecule(palpha)
+
+	if err != nil {
+		log.Panicf("failed to parse alphabet: %v", err)
+	}
+
+	enc, err := seqio.ParseEncoding(phred)
+
+	if err != nil {
+		log.Panicf("failed to parse quality encoding: %v", err)
+	}
+
 	var fin, fout *os.File
 
 	if pin == "" {
@@ -91,7 +283,35 @@ func main() {
 		}
 	}()
 
-	w := bufio.NewWriter(fout)
+	in, err := seqio.Open(fin, format, mol, enc)
+
+	if err != nil {
+		log.Panicf("failed to open %q: %v", pin, err)
+	}
+
+	if _, ok := in.Molecule.Alphabet().(alphabet.Complementor); !ok && strand == derep.Both {
+		log.Panicf("failed to dereplicate %q: %v cannot be reverse complemented", pin, in.Molecule)
+	}
+
+	defer func() {
+		if err := in.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", pin, err)
+		}
+	}()
+
+	z, err := seqio.NewCompressor(fout, seqio.CompressionByExt(pout), level)
+
+	if err != nil {
+		log.Panicf("failed to compress %q: %v", pout, err)
+	}
+
+	defer func() {
+		if err := z.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", pout, err)
+		}
+	}()
+
+	w := bufio.NewWriter(z)
 
 	defer func() {
 		if err := w.Flush(); err != nil {
@@ -99,10 +319,74 @@ func main() {
 		}
 	}()
 
-	c := make(chan *linear.Seq)
+	var ucw *uc.Writer
+
+	if puc != "" {
+		f, err := os.Create(puc)
+
+		if err != nil {
+			log.Panicf("failed to open %q: %v", puc, err)
+		}
+
+		defer func() {
+			if err := f.Close(); err != nil {
+				log.Panicf("failed to close %q: %v", puc, err)
+			}
+		}()
+
+		z, err := seqio.NewCompressor(f, seqio.CompressionByExt(puc), level)
+
+		if err != nil {
+			log.Panicf("failed to compress %q: %v", puc, err)
+		}
+
+		defer func() {
+			if err := z.Close(); err != nil {
+				log.Panicf("failed to close %q: %v", puc, err)
+			}
+		}()
+
+		b := bufio.NewWriter(z)
+
+		defer func() {
+			if err := b.Flush(); err != nil {
+				log.Panicf("failed to flush %q: %v", puc, err)
+			}
+		}()
 
-	wg.Add(2)
-	go seqio.ScanSeq(fin, c, &wg)       // TODO: handling panic
-	go derep.DeRep(c, w, min, max, &wg) // TODO: handling panic
-	wg.Wait()
+		ucw = uc.NewWriter(b)
+	}
+
+	c := make(chan seq.Sequence)
+
+	g, ctx := errgroup.WithContext(context.Background())
+
+	g.Go(func() error {
+		return seqio.ScanContext(ctx, in, c)
+	})
+
+	g.Go(func() error {
+		return derep.Run(
+			ctx, c, seqio.NewWriter(w, in.Format),
+			derep.Options{
+				Min:        min,
+				Max:        max,
+				Policies:   policies,
+				Strand:     strand,
+				Mode:       mode,
+				LowMemory:  low,
+				DropMerged: drop,
+				MaxMemory:  maxMem,
+				TempDir:    ptmp,
+				Order:      order,
+				Relabel:    rl,
+				UC:         ucw,
+				Threads:    threads,
+			},
+		)
+	})
+
+	if err := g.Wait(); err != nil {
+		log.Panicf("failed to dereplicate %q: %v", pin, err)
+	}
 }
@@ -17,7 +17,27 @@
 /*
 DeRep removes duplications from FASTA sequences and sums the sequence abundance.
 
-DeRep scans through a FASTA file and builds a map using the sequence as key.
+DeRep scans through a FASTA or FASTQ file and builds a map using the sequence
+as key. The output is written in the format of the input. When the input is
+FASTQ, each position keeps the highest quality among the merged sequences.
+
+The output is sorted by -sort, so the same input always gives the same output:
+	abundance	the most abundant first, then by name, the default.
+	input		in the order of the first sequence of each cluster.
+	length		the longest first, then the most abundant.
+	sequence	by the letters in lexicographical order.
+The ties are kept in the order of the input.
+
+DeRep names each output sequence after its first sequence. With -relabel, the
+sequences are renamed by the prefix and a counter in the output order, such as
+Uniq1 and Uniq2. With -relabel-sha1 or -relabel-md5, they are renamed by the
+digest of their letters in upper case, which names the same sequence the same
+across runs. With -relabel-keep, the original name is kept as "label=".
+
+DeRep detects the format of the input by its first character, and reads input
+compressed by gzip, bzip2 or Zstandard without decompressing it first. The
+output is compressed by the extension of its path: ".gz" for gzip, ".bz2" for
+bzip2 and ".zst" for Zstandard. Gzip output is compressed on all CPUs.
 
 DeRep requires the FASTA header of a sequence to be a semicolon delimited list:
 	> NM_000518;HBB;size=628;organism=9606
@@ -32,25 +52,117 @@ DeRep uses the last key-value pair, a field that has an equal sign, with "size"
 as key and an integer as value for the abundance of that sequence. If such pair
 does not exist in the header, the size of the sequence defaults to 1.
 
+DeRep keeps the other fields of the header, such as "sample=" and
+"barcodelabel=", in their order and writes them back before the size. When the
+duplicates of a sequence disagree on a key, the value is merged by the policy
+of the key given by -merge:
+	keep-first	keep the value of the first sequence, the default.
+	concat		join the distinct values by commas.
+	sum		add the values if all of them are numbers.
+	drop		remove the key unless every sequence has the same value.
+The policy of the key "*" applies to the keys without their own policy. The
+other monads are kept from the first sequence.
+
+With -strand both, DeRep also merges a sequence with its reverse complement,
+as the --strand both option of vsearch. The output keeps the orientation of the
+first sequence, and the merged sequences in reverse orientation are recorded
+with the minus strand.
+
+DeRep reads nucleotides of DNA by default. With -alphabet rna or protein, the
+input is read as RNA or amino acids, and with -alphabet auto, the alphabet is
+detected by the letters at the start of the input. Amino acids cannot be
+dereplicated with -strand both.
+
+With -mode prefix, DeRep also merges a sequence into a longer sequence that
+starts with it, as the --derep_prefix option of vsearch. If several sequences
+start with it, the longest one is chosen, then the most abundant one, then the
+first one. The prefix mode only compares the plus strand.
+
+With -uc, DeRep also writes each cluster in the UC format of vsearch: an S
+record of its first sequence, an H record of each merged sequence with its
+length, identity and strand, and a C record of the cluster. The three records
+label the cluster by the name written to the output. The UC file is
+compressed by its extension as the output, and cannot be written with
+-drop-merged or -max-memory.
+
+With -low-memory, DeRep keys the sequences by a 128-bit hash instead of their
+letters, and stores each unique sequence once in 2 bits per nucleotide. The
+letters are compared when two hashes are the same, so the output is the same
+as the default. With -drop-merged, DeRep also discards the headers of the
+merged sequences, which it otherwise keeps until the output is written.
+
+With -max-memory, DeRep holds the unique sequences in memory until their
+estimated size reaches the budget, such as 512M or 8G, then sorts them and
+spills them to a file in -temp-dir. The spilled files are merged at the end,
+summing the sequences found in several files, and the output is the same as
+without the budget. The prefix mode does not support -max-memory.
+
+DeRep dereplicates on -threads goroutines, each holding the sequences whose
+hash falls in its shard. The output does not depend on the number of threads.
+With -max-memory, DeRep runs on a single thread.
+
 Usage:
 	derep [flags]
 
 The flags are:
+	-alphabet string
+		alphabet of the input file, auto, dna, rna or protein, default to dna.
+	-compress-level int
+		compression level of the output file, default to 0 for the default level.
+	-drop-merged
+		discard the headers of merged sequences, default to false.
+	-format string
+		format of the input file, auto, fasta or fastq, default to auto.
 	-in string
 		path to the sequence FASTA file, default to stdin.
+	-low-memory
+		key sequences by hash and store them in 2 bits, default to false.
 	-max int
 		maximal abundance of a sequence, default to 0.
+	-max-memory string
+		memory of unique sequences before spilling to disk, such as 8G, default to 0 for unlimited.
+	-merge string
+		comma separated key=policy to merge header attributes, default to keep-first.
 	-min int
 		minimal abundance of a sequence, default to 0.
+	-mode string
+		mode to match sequences, full or prefix, default to full.
 	-out string
-		path to the output FASTA file, default to stdout.
+		path to the output FASTA file, compressed by its extension, default to stdout.
+	-phred int
+		Phred offset of the FASTQ quality, 33 or 64, default to 33.
+	-relabel string
+		prefix to relabel sequences with a counter, default to no relabeling.
+	-relabel-keep
+		keep the original label as the label attribute, default to false.
+	-relabel-md5
+		relabel sequences with the MD5 digest of their letters, default to false.
+	-relabel-sha1
+		relabel sequences with the SHA1 digest of their letters, default to false.
+	-sort string
+		order of the output, abundance, input, length or sequence, default to abundance.
+	-strand string
+		strand to compare, plus or both, default to plus.
+	-temp-dir string
+		directory of the spilled sequences, default to the temporary directory.
+	-threads int
+		number of threads to dereplicate, default to the number of CPUs.
+	-uc string
+		path to the output UC file, compressed by its extension, default to none.
 
 Example:
 	derep -in short.fasta -out merged.fasta
-	gunzip -c compress_seq.fasta.gz | derep -out merged.fasta
-	derep -in short.fasta | grep ">" | sort
+	derep -in compress_seq.fasta.gz -out merged.fasta
+	derep -in short.fasta -out merged.fasta.gz -compress-level 9
+	derep -in short.fasta -sort length
+	derep -phred 64 -in reads.fastq.zst -out merged.fastq
+	derep -in all.fasta -merge "*=drop,sample=concat"
+	derep -in amplicons.fasta -strand both
+	derep -in all.fasta -relabel-sha1 -relabel-keep
+	derep -in all.fasta -out all.derep.fasta -uc all.derep.uc
+	derep -in trimmed.fasta -mode prefix
+	derep -in orfs.faa -out orfs.derep.faa -alphabet protein
+	derep -in huge.fastq.gz -out merged.fastq.gz -low-memory -drop-merged
+	derep -in study.fasta.zst -out merged.fasta -max-memory 48G -temp-dir /scratch
 */
 package main
-
-// BUG(mys721tx): Currently DeRep does not preserve original FASTA header other
-// than the name and abundance of the sequence.
@@ -0,0 +1,370 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"runtime"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/mys721tx/gsearch/pkg/derep"
+	"github.com/mys721tx/gsearch/pkg/kmer"
+	"github.com/mys721tx/gsearch/pkg/search"
+	"github.com/mys721tx/gsearch/pkg/seqio"
+	"github.com/mys721tx/gsearch/pkg/uc"
+)
+
+var (
+	pin = flag.String(
+		"in",
+		"",
+		"path to the query sequence file, default to stdin.",
+	)
+	pdb = flag.String(
+		"db",
+		"",
+		"path to the reference database sequence file.",
+	)
+	pfmt = flag.String(
+		"format",
+		"auto",
+		"format of the query file, auto, fasta or fastq, default to auto.",
+	)
+	pdbfmt = flag.String(
+		"db-format",
+		"auto",
+		"format of the database file, auto, fasta or fastq, default to auto.",
+	)
+	phred = flag.Int(
+		"phred",
+		33,
+		"Phred offset of the FASTQ quality, 33 or 64, default to 33.",
+	)
+	palpha = flag.String(
+		"alphabet",
+		"dna",
+		"alphabet of the database file, auto, dna, rna or protein, default to dna.",
+	)
+	id = flag.Float64(
+		"id",
+		search.DefaultIdentity,
+		"minimal identity of a hit, from 0 to 1, default to 0.97.",
+	)
+	accepts = flag.Int(
+		"maxaccepts",
+		kmer.DefaultMaxAccepts,
+		"targets above -id to accept before stopping the search, default to 1, 0 for unlimited.",
+	)
+	rejects = flag.Int(
+		"maxrejects",
+		kmer.DefaultMaxRejects,
+		"targets below -id to reject before stopping the search, default to 32, 0 for unlimited.",
+	)
+	maxHits = flag.Int(
+		"maxhits",
+		0,
+		"maximal number of hits of a query, default to 0 for all the accepted targets.",
+	)
+	exact = flag.Bool(
+		"exact",
+		false,
+		"search the targets identical to the queries by a hash index without alignment, default to false.",
+	)
+	pstrand = flag.String(
+		"strand",
+		"plus",
+		"strand of the queries searched, plus or both, default to plus.",
+	)
+	threads = flag.Int(
+		"threads",
+		runtime.NumCPU(),
+		"number of queries searched in parallel, default to the number of CPUs.",
+	)
+	pblast6 = flag.String(
+		"blast6out",
+		"",
+		"path to the output BLAST tabular file, compressed by its extension, default to none.",
+	)
+	puc = flag.String(
+		"uc",
+		"",
+		"path to the output UC file, compressed by its extension, default to none.",
+	)
+	puser = flag.String(
+		"userout",
+		"",
+		"path to the output file of -userfields, compressed by its extension, default to none.",
+	)
+	pfields = flag.String(
+		"userfields",
+		"query+target+id",
+		"fields of -userout separated by +, default to query+target+id.",
+	)
+	noHits = flag.Bool(
+		"output-no-hits",
+		false,
+		"write the queries without hit to -blast6out and -userout, default to false.",
+	)
+	level = flag.Int(
+		"compress-level",
+		seqio.DefaultLevel,
+		"compression level of the output files, default to 0 for the default level.",
+	)
+)
+
+// create creates a buffered output file compressed by its extension. The
+// returned function flushes and closes the file.
+func create(path string) (*bufio.Writer, func()) {
+	f, err := os.Create(path)
+
+	if err != nil {
+		log.Panicf("failed to open %q: %v", path, err)
+	}
+
+	z, err := seqio.NewCompressor(f, seqio.CompressionByExt(path), *level)
+
+	if err != nil {
+		log.Panicf("failed to compress %q: %v", path, err)
+	}
+
+	b := bufio.NewWriter(z)
+
+	return b, func() {
+		if err := b.Flush(); err != nil {
+			log.Panicf("failed to flush %q: %v", path, err)
+		}
+
+		if err := z.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", path, err)
+		}
+
+		if err := f.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", path, err)
+		}
+	}
+}
+
+// readDB reads the targets of the database and returns it with their molecule.
+func readDB(format seqio.Format, mol seqio.Molecule, enc alphabet.Encoding) (*search.DB, seqio.Molecule) {
+	f, err := os.Open(*pdb)
+
+	if err != nil {
+		log.Panicf("failed to open %q: %v", *pdb, err)
+	}
+
+	in, err := seqio.Open(f, format, mol, enc)
+
+	if err != nil {
+		log.Panicf("failed to open %q: %v", *pdb, err)
+	}
+
+	defer func() {
+		if err := in.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", *pdb, err)
+		}
+
+		if err := f.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", *pdb, err)
+		}
+	}()
+
+	k := kmer.DefaultK
+
+	if in.Molecule == seqio.Protein {
+		k = kmer.DefaultProteinK
+	}
+
+	db, err := search.NewDBOf(k, in.Molecule.Alphabet().Moltype())
+
+	if err != nil {
+		log.Panicf("failed to index %q: %v", *pdb, err)
+	}
+
+	for {
+		s, err := in.Read()
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			log.Panicf("failed to read %q: %v", *pdb, err)
+		}
+
+		db.Add(seqio.AsSeq(s))
+	}
+
+	return db, in.Molecule
+}
+
+func main() {
+	flag.Parse()
+
+	if *pdb == "" {
+		log.Panicf("failed to search: -db is required")
+	}
+
+	strand, err := derep.ParseStrand(*pstrand)
+
+	if err != nil {
+		log.Panicf("failed to parse strand: %v", err)
+	}
+
+	fields, err := search.ParseFields(*pfields)
+
+	if err != nil {
+		log.Panicf("failed to parse fields: %v", err)
+	}
+
+	format, err := seqio.ParseFormat(*pfmt)
+
+	if err != nil {
+		log.Panicf("failed to parse format: %v", err)
+	}
+
+	dbFormat, err := seqio.ParseFormat(*pdbfmt)
+
+	if err != nil {
+		log.Panicf("failed to parse database format: %v", err)
+	}
+
+	enc, err := seqio.ParseEncoding(*phred)
+
+	if err != nil {
+		log.Panicf("failed to parse quality encoding: %v", err)
+	}
+
+	mol, err := seqio.ParseMolecule(*palpha)
+
+	if err != nil {
+		log.Panicf("failed to parse alphabet: %v", err)
+	}
+
+	// The queries are read as the molecule of the database.
+	db, mol := readDB(dbFormat, mol, enc)
+
+	s, err := search.New(db, search.Options{
+		Identity:   *id,
+		MaxAccepts: *accepts,
+		MaxRejects: *rejects,
+		MaxHits:    *maxHits,
+		Strand:     strand,
+		Threads:    *threads,
+		Exact:      *exact,
+	})
+
+	if err != nil {
+		log.Panicf("failed to search: %v", err)
+	}
+
+	var fin *os.File
+
+	if *pin == "" {
+		fin = os.Stdin
+	} else if f, err := os.Open(*pin); err == nil {
+		fin = f
+	} else {
+		log.Panicf("failed to open %q: %v", *pin, err)
+	}
+
+	in, err := seqio.Open(fin, format, mol, enc)
+
+	if err != nil {
+		log.Panicf("failed to open %q: %v", *pin, err)
+	}
+
+	defer func() {
+		if err := in.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", *pin, err)
+		}
+	}()
+
+	var blast6, user *search.Writer
+	var ucw *uc.Writer
+
+	if *pblast6 != "" {
+		w, done := create(*pblast6)
+		defer done()
+		blast6 = search.NewWriter(w, db, search.Blast6)
+	}
+
+	if *puser != "" {
+		w, done := create(*puser)
+		defer done()
+		user = search.NewWriter(w, db, fields)
+	}
+
+	if *puc != "" {
+		w, done := create(*puc)
+		defer done()
+		ucw = uc.NewWriter(w)
+	}
+
+	ch := make(chan seq.Sequence)
+
+	g, ctx := errgroup.WithContext(context.Background())
+
+	g.Go(func() error {
+		return seqio.ScanContext(ctx, in, ch)
+	})
+
+	g.Go(func() error {
+		return s.Run(ctx, ch, func(r *search.Result) error {
+			for _, w := range []*search.Writer{blast6, user} {
+				if w == nil {
+					continue
+				}
+
+				if err := w.Write(r); err != nil {
+					return err
+				}
+
+				if len(r.Hits) == 0 && *noHits {
+					if err := w.WriteNoHit(r.Query); err != nil {
+						return err
+					}
+				}
+			}
+
+			if ucw == nil {
+				return nil
+			}
+
+			if len(r.Hits) == 0 {
+				return ucw.WriteNoHit(r.Query.ID, r.Query.Len())
+			}
+
+			for _, h := range r.UC(db) {
+				if err := ucw.WriteHit(h); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	})
+
+	if err := g.Wait(); err != nil {
+		log.Panicf("failed to search %q: %v", *pin, err)
+	}
+}
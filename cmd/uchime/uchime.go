@@ -0,0 +1,364 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/mys721tx/gsearch/pkg/chimera"
+	"github.com/mys721tx/gsearch/pkg/cluster"
+	"github.com/mys721tx/gsearch/pkg/seqio"
+)
+
+var (
+	pin = flag.String(
+		"in",
+		"",
+		"path to the dereplicated sequence file, default to stdin.",
+	)
+	pdb = flag.String(
+		"db",
+		"",
+		"path to the reference database file to detect chimeras against, default to none for de novo.",
+	)
+	pfmt = flag.String(
+		"format",
+		"auto",
+		"format of the input file, auto, fasta or fastq, default to auto.",
+	)
+	pdbfmt = flag.String(
+		"db-format",
+		"auto",
+		"format of the database file, auto, fasta or fastq, default to auto.",
+	)
+	phred = flag.Int(
+		"phred",
+		33,
+		"Phred offset of the FASTQ quality, 33 or 64, default to 33.",
+	)
+	pchim = flag.String(
+		"chimeras",
+		"",
+		"path to the output file of chimeras, compressed by its extension, default to none.",
+	)
+	pnonchim = flag.String(
+		"nonchimeras",
+		"",
+		"path to the output file of non-chimeras, compressed by its extension, default to none.",
+	)
+	pborder = flag.String(
+		"borderline",
+		"",
+		"path to the output file of borderline sequences, compressed by its extension, default to none.",
+	)
+	pout = flag.String(
+		"uchimeout",
+		"",
+		"path to the output uchimeout table, compressed by its extension, default to none.",
+	)
+	abskew = flag.Float64(
+		"abskew",
+		chimera.DefaultAbSkew,
+		"minimal abundance of a parent over the abundance of the query de novo, default to 16.",
+	)
+	minh = flag.Float64(
+		"minh",
+		chimera.DefaultMinH,
+		"minimal score of a chimera, default to 0.28.",
+	)
+	xn = flag.Float64(
+		"xn",
+		chimera.DefaultXN,
+		"weight of the no votes, default to 8.",
+	)
+	dn = flag.Float64(
+		"dn",
+		chimera.DefaultDN,
+		"pseudo-count of the no votes, default to 1.4.",
+	)
+	mindiffs = flag.Int(
+		"mindiffs",
+		chimera.DefaultMinDiffs,
+		"minimal number of yes votes on each side of a chimera, default to 3.",
+	)
+	mindiv = flag.Float64(
+		"mindiv",
+		chimera.DefaultMinDiv,
+		"minimal divergence of a chimera from its closest parent in percentage points, default to 0.8.",
+	)
+	threads = flag.Int(
+		"threads",
+		runtime.NumCPU(),
+		"number of queries checked against -db in parallel, default to the number of CPUs.",
+	)
+	level = flag.Int(
+		"compress-level",
+		seqio.DefaultLevel,
+		"compression level of the output files, default to 0 for the default level.",
+	)
+)
+
+// create creates a buffered output file compressed by its extension. The
+// returned function flushes and closes the file.
+func create(path string) (*bufio.Writer, func()) {
+	f, err := os.Create(path)
+
+	if err != nil {
+		log.Panicf("failed to open %q: %v", path, err)
+	}
+
+	z, err := seqio.NewCompressor(f, seqio.CompressionByExt(path), *level)
+
+	if err != nil {
+		log.Panicf("failed to compress %q: %v", path, err)
+	}
+
+	b := bufio.NewWriter(z)
+
+	return b, func() {
+		if err := b.Flush(); err != nil {
+			log.Panicf("failed to flush %q: %v", path, err)
+		}
+
+		if err := z.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", path, err)
+		}
+
+		if err := f.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", path, err)
+		}
+	}
+}
+
+// readClusters reads the sequences of an input of a path as clusters.
+func readClusters(in *seqio.Input, path string) []*cluster.Cluster {
+	var cs []*cluster.Cluster
+
+	for {
+		s, err := in.Read()
+
+		if err == io.EOF {
+			return cs
+		} else if err != nil {
+			log.Panicf("failed to read %q: %v", path, err)
+		}
+
+		cs = append(cs, cluster.ParseAnno(s))
+	}
+}
+
+// readDB adds the sequences of the database as the parents of d.
+func readDB(d *chimera.Detector, format seqio.Format, enc alphabet.Encoding) {
+	f, err := os.Open(*pdb)
+
+	if err != nil {
+		log.Panicf("failed to open %q: %v", *pdb, err)
+	}
+
+	in, err := seqio.Open(f, format, seqio.AutoMolecule, enc)
+
+	if err != nil {
+		log.Panicf("failed to open %q: %v", *pdb, err)
+	}
+
+	if in.Molecule != seqio.DNA {
+		log.Panicf(
+			"failed to read %q: %v is not supported, uchime only reads dna",
+			*pdb, in.Molecule,
+		)
+	}
+
+	defer func() {
+		if err := in.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", *pdb, err)
+		}
+
+		if err := f.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", *pdb, err)
+		}
+	}()
+
+	for _, c := range readClusters(in, *pdb) {
+		d.AddParent(c)
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	opt := chimera.Options{
+		AbSkew:   *abskew,
+		MinH:     *minh,
+		XN:       *xn,
+		DN:       *dn,
+		MinDiffs: *mindiffs,
+		MinDiv:   *mindiv,
+	}
+
+	// The parents of the reference are trusted regardless of their abundance.
+	if *pdb != "" {
+		opt.AbSkew = 0
+	}
+
+	d, err := chimera.New(opt)
+
+	if err != nil {
+		log.Panicf("failed to detect chimeras: %v", err)
+	}
+
+	format, err := seqio.ParseFormat(*pfmt)
+
+	if err != nil {
+		log.Panicf("failed to parse format: %v", err)
+	}
+
+	dbFormat, err := seqio.ParseFormat(*pdbfmt)
+
+	if err != nil {
+		log.Panicf("failed to parse database format: %v", err)
+	}
+
+	enc, err := seqio.ParseEncoding(*phred)
+
+	if err != nil {
+		log.Panicf("failed to parse quality encoding: %v", err)
+	}
+
+	if *pdb != "" {
+		readDB(d, dbFormat, enc)
+	}
+
+	var fin *os.File
+
+	if *pin == "" {
+		fin = os.Stdin
+	} else if f, err := os.Open(*pin); err == nil {
+		fin = f
+	} else {
+		log.Panicf("failed to open %q: %v", *pin, err)
+	}
+
+	// The chimeras are scored over the letters of DNA. The molecule of the
+	// input is detected so that RNA and proteins are rejected.
+	in, err := seqio.Open(fin, format, seqio.AutoMolecule, enc)
+
+	if err != nil {
+		log.Panicf("failed to open %q: %v", *pin, err)
+	}
+
+	if in.Molecule != seqio.DNA {
+		log.Panicf(
+			"failed to read %q: %v is not supported, uchime only reads dna",
+			*pin, in.Molecule,
+		)
+	}
+
+	defer func() {
+		if err := in.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", *pin, err)
+		}
+	}()
+
+	paths := map[chimera.Verdict]string{
+		chimera.Chimera:    *pchim,
+		chimera.NonChimera: *pnonchim,
+		chimera.Borderline: *pborder,
+	}
+
+	// outs holds the writers of the sequences of each verdict.
+	outs := make(map[chimera.Verdict]seqio.Writer)
+
+	for v, path := range paths {
+		if path == "" {
+			continue
+		}
+
+		w, done := create(path)
+		defer done()
+
+		outs[v] = seqio.NewWriter(w, in.Format)
+	}
+
+	var table *chimera.Writer
+
+	if *pout != "" {
+		w, done := create(*pout)
+		defer done()
+
+		table = chimera.NewWriter(w)
+	}
+
+	write := func(r *chimera.Result) error {
+		if w, ok := outs[r.Verdict]; ok {
+			if _, err := w.Write(r.Query); err != nil {
+				return fmt.Errorf("failed to write %q: %w", paths[r.Verdict], err)
+			}
+		}
+
+		if table != nil {
+			if err := table.Write(r); err != nil {
+				return fmt.Errorf("failed to write %q: %w", *pout, err)
+			}
+		}
+
+		return nil
+	}
+
+	if *pdb != "" {
+		ch := make(chan seq.Sequence)
+
+		g, ctx := errgroup.WithContext(context.Background())
+
+		g.Go(func() error { return seqio.ScanContext(ctx, in, ch) })
+		g.Go(func() error { return d.Run(ctx, ch, *threads, write) })
+
+		if err := g.Wait(); err != nil {
+			log.Panicf("failed to detect chimeras of %q: %v", *pin, err)
+		}
+
+		return
+	}
+
+	cs := readClusters(in, *pin)
+
+	// The parents are searched among the more abundant sequences.
+	sort.Stable(cluster.ByAbundance(cs))
+
+	for _, c := range cs {
+		r, err := d.Add(c)
+
+		if err != nil {
+			log.Panicf("failed to detect chimeras of %q: %v", *pin, err)
+		}
+
+		if err := write(r); err != nil {
+			log.Panicf("failed to detect chimeras of %q: %v", *pin, err)
+		}
+	}
+}
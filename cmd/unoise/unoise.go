@@ -0,0 +1,270 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/mys721tx/gsearch/pkg/centroid"
+	"github.com/mys721tx/gsearch/pkg/cluster"
+	"github.com/mys721tx/gsearch/pkg/kmer"
+	"github.com/mys721tx/gsearch/pkg/relabel"
+	"github.com/mys721tx/gsearch/pkg/seqio"
+	"github.com/mys721tx/gsearch/pkg/uc"
+)
+
+var (
+	pin = flag.String(
+		"in",
+		"",
+		"path to the dereplicated sequence file, default to stdin.",
+	)
+	pout = flag.String(
+		"out",
+		"",
+		"path to the output zOTU file, compressed by its extension, default to stdout.",
+	)
+	puc = flag.String(
+		"uc",
+		"",
+		"path to the output UC file, compressed by its extension, default to none.",
+	)
+	pfmt = flag.String(
+		"format",
+		"auto",
+		"format of the input file, auto, fasta or fastq, default to auto.",
+	)
+	phred = flag.Int(
+		"phred",
+		33,
+		"Phred offset of the FASTQ quality, 33 or 64, default to 33.",
+	)
+	level = flag.Int(
+		"compress-level",
+		seqio.DefaultLevel,
+		"compression level of the output files, default to 0 for the default level.",
+	)
+	alpha = flag.Float64(
+		"unoise_alpha",
+		centroid.DefaultUnoiseAlpha,
+		"alpha of the maximal skew 1/2^(alpha*d+1) of a sequence of distance d to its zOTU, default to 2.",
+	)
+	minSize = flag.Int(
+		"minsize",
+		centroid.DefaultMinSize,
+		"minimal abundance of a sequence to denoise, default to 8.",
+	)
+	accepts = flag.Int(
+		"maxaccepts",
+		kmer.DefaultMaxAccepts,
+		"zOTUs within the skew to accept before stopping the search, default to 1, 0 for unlimited.",
+	)
+	rejects = flag.Int(
+		"maxrejects",
+		kmer.DefaultMaxRejects,
+		"zOTUs beyond the skew to reject before stopping the search, default to 32, 0 for unlimited.",
+	)
+	prelabel = flag.String(
+		"relabel",
+		"",
+		"prefix to relabel zOTUs with a counter, such as Zotu, default to no relabeling.",
+	)
+	sha1 = flag.Bool(
+		"relabel-sha1",
+		false,
+		"relabel zOTUs with the SHA1 digest of their letters, default to false.",
+	)
+	md5 = flag.Bool(
+		"relabel-md5",
+		false,
+		"relabel zOTUs with the MD5 digest of their letters, default to false.",
+	)
+	keep = flag.Bool(
+		"relabel-keep",
+		false,
+		"keep the original label as the label attribute, default to false.",
+	)
+)
+
+// create creates a buffered output file compressed by its extension, or
+// stdout if the path is empty. The returned function flushes and closes the
+// file.
+func create(path string) (*bufio.Writer, func()) {
+	f := os.Stdout
+
+	if path != "" {
+		var err error
+
+		if f, err = os.Create(path); err != nil {
+			log.Panicf("failed to open %q: %v", path, err)
+		}
+	}
+
+	z, err := seqio.NewCompressor(f, seqio.CompressionByExt(path), *level)
+
+	if err != nil {
+		log.Panicf("failed to compress %q: %v", path, err)
+	}
+
+	b := bufio.NewWriter(z)
+
+	return b, func() {
+		if err := b.Flush(); err != nil {
+			log.Panicf("failed to flush %q: %v", path, err)
+		}
+
+		if err := z.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", path, err)
+		}
+
+		if err := f.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", path, err)
+		}
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	if *minSize < 1 {
+		log.Panicf("failed to denoise: -minsize should be at least 1")
+	}
+
+	cl, err := centroid.New(centroid.Options{
+		MaxAccepts:  *accepts,
+		MaxRejects:  *rejects,
+		UnoiseAlpha: *alpha,
+	})
+
+	if err != nil {
+		log.Panicf("failed to denoise: %v", err)
+	}
+
+	rl, err := relabel.New(relabel.Options{
+		Prefix: *prelabel,
+		SHA1:   *sha1,
+		MD5:    *md5,
+		Keep:   *keep,
+	})
+
+	if err != nil {
+		log.Panicf("failed to relabel: %v", err)
+	}
+
+	format, err := seqio.ParseFormat(*pfmt)
+
+	if err != nil {
+		log.Panicf("failed to parse format: %v", err)
+	}
+
+	enc, err := seqio.ParseEncoding(*phred)
+
+	if err != nil {
+		log.Panicf("failed to parse quality encoding: %v", err)
+	}
+
+	var fin *os.File
+
+	if *pin == "" {
+		fin = os.Stdin
+	} else if f, err := os.Open(*pin); err == nil {
+		fin = f
+	} else {
+		log.Panicf("failed to open %q: %v", *pin, err)
+	}
+
+	// UNOISE models the errors of DNA amplicons. The molecule of the input is
+	// detected so that RNA and proteins are rejected.
+	in, err := seqio.Open(fin, format, seqio.AutoMolecule, enc)
+
+	if err != nil {
+		log.Panicf("failed to open %q: %v", *pin, err)
+	}
+
+	if in.Molecule != seqio.DNA {
+		log.Panicf(
+			"failed to read %q: %v is not supported, unoise only reads dna",
+			*pin, in.Molecule,
+		)
+	}
+
+	defer func() {
+		if err := in.Close(); err != nil {
+			log.Panicf("failed to close %q: %v", *pin, err)
+		}
+	}()
+
+	var cs []*cluster.Cluster
+
+	for {
+		s, err := in.Read()
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			log.Panicf("failed to read %q: %v", *pin, err)
+		}
+
+		// The sequences below -minsize are discarded as noise.
+		if c := cluster.ParseAnno(s); c.Size >= *minSize {
+			cs = append(cs, c)
+		}
+	}
+
+	sort.Stable(cluster.ByAbundance(cs))
+
+	for _, c := range cs {
+		if _, err := cl.Add(c); err != nil {
+			log.Panicf("failed to denoise %q: %v", *pin, err)
+		}
+	}
+
+	b, done := create(*pout)
+	defer done()
+
+	w := seqio.NewWriter(b, in.Format)
+
+	var ucw *uc.Writer
+
+	if *puc != "" {
+		b, done := create(*puc)
+		defer done()
+
+		ucw = uc.NewWriter(b)
+	}
+
+	for _, c := range cl.Centroids() {
+		rl.Cluster(c)
+
+		if _, err := w.Write(c); err != nil {
+			log.Panicf("failed to write %q: %v", *pout, err)
+		}
+
+		if ucw == nil {
+			continue
+		}
+
+		if err := ucw.Write(c); err != nil {
+			log.Panicf("failed to write %q: %v", *puc, err)
+		}
+	}
+}
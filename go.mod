@@ -5,6 +5,10 @@ go 1.14
 require (
 	github.com/biogo/biogo v1.0.2
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/dsnet/compress v0.0.1
+	github.com/klauspost/compress v1.11.13
+	github.com/klauspost/pgzip v1.2.5
 	github.com/stretchr/objx v0.1.1 // indirect
 	github.com/stretchr/testify v1.6.1
+	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
 )
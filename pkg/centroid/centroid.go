@@ -0,0 +1,205 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package centroid provides the greedy centroid clustering of sequences, as
+// the --cluster_size and --cluster_fast commands of vsearch, and the denoising
+// of amplicons, as the --cluster_unoise command.
+package centroid
+
+import (
+	"errors"
+	"math"
+
+	"github.com/biogo/biogo/align"
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/feat"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+	"github.com/mys721tx/gsearch/pkg/kmer"
+	"github.com/mys721tx/gsearch/pkg/pairwise"
+)
+
+// DefaultIdentity is the default minimal identity to join a centroid.
+const DefaultIdentity = 0.97
+
+// The default options of denoising, as the --cluster_unoise command of
+// vsearch.
+const (
+	DefaultUnoiseAlpha = 2
+	DefaultMinSize     = 8
+)
+
+// Options configures a Clusterer.
+type Options struct {
+	// Identity is the minimal identity to join a centroid, from 0 to 1.
+	Identity float64
+	// Moltype is the molecule of the sequences, DNA by default. The sequences
+	// are aligned over pairwise.AlphabetOf the molecule.
+	Moltype feat.Moltype
+	// Aligner aligns the sequences to the centroids, or nil for
+	// pairwise.NewAlignerOf the molecule.
+	Aligner align.Aligner
+	// K is the length of the words indexing the centroids, or 0 for
+	// kmer.DefaultK, or kmer.DefaultProteinK for proteins.
+	K int
+	// MaxAccepts and MaxRejects limit the centroids aligned to a sequence, as
+	// kmer.Index.Search. A limit of 0 is unlimited.
+	MaxAccepts, MaxRejects int
+	// UnoiseAlpha, if positive, denoises the sequences as UNOISE3: a sequence
+	// only joins a centroid if its skew, its abundance over the abundance of
+	// the centroid, is at most Beta(UnoiseAlpha, d) for the edit distance d
+	// of their alignment.
+	UnoiseAlpha float64
+}
+
+var (
+	errIdentity = errors.New("centroid: identity should be between 0 and 1")
+	errAlpha    = errors.New("centroid: alpha should not be negative")
+)
+
+// Beta returns the maximal skew of a sequence of edit distance d to its
+// centroid, 1/2^(alpha*d+1), as UNOISE3.
+func Beta(alpha float64, d int) float64 {
+	return math.Pow(2, -(alpha*float64(d) + 1))
+}
+
+// Clusterer clusters sequences greedily: each sequence joins the centroid of
+// the highest identity at least Options.Identity, or becomes a new centroid.
+// The sequences are expected in the order of decreasing abundance, as sorted by
+// cluster.ByAbundance.
+//
+// The centroids are indexed by their words, and only the centroids sharing
+// words with a sequence are aligned to it, in the order of the shared words.
+type Clusterer struct {
+	opt       Options
+	alpha     alphabet.Alphabet
+	index     *kmer.Index
+	centroids []*cluster.Cluster
+	// sizes holds the abundance of each centroid before any sequence joins
+	// it.
+	sizes []int
+}
+
+// New returns a Clusterer without any centroid.
+func New(opt Options) (*Clusterer, error) {
+	if opt.Identity < 0 || opt.Identity > 1 {
+		return nil, errIdentity
+	}
+
+	if opt.UnoiseAlpha < 0 {
+		return nil, errAlpha
+	}
+
+	if opt.Aligner == nil {
+		opt.Aligner = pairwise.NewAlignerOf(opt.Moltype)
+	}
+
+	switch {
+	case opt.K != 0:
+	case opt.Moltype == feat.Protein:
+		opt.K = kmer.DefaultProteinK
+	default:
+		opt.K = kmer.DefaultK
+	}
+
+	ix, err := kmer.NewIndexOf(opt.K, opt.Moltype)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Clusterer{
+		opt:   opt,
+		alpha: pairwise.AlphabetOf(opt.Moltype),
+		index: ix,
+	}, nil
+}
+
+// Add compares a cluster to the candidate centroids within
+// Options.MaxAccepts and Options.MaxRejects. If the highest identity is at
+// least Options.Identity, the cluster joins that centroid, the first compared
+// among ties; otherwise it becomes a new centroid. Add returns the centroid of
+// the cluster.
+//
+// A cluster joining a centroid adds its size to the centroid and its members
+// to Merged, with their identity to the centroid in percent.
+func (g *Clusterer) Add(c *cluster.Cluster) (*cluster.Cluster, error) {
+	var best *cluster.Cluster
+	var id float64
+
+	err := g.index.Search(
+		c.Seq.Seq, g.opt.MaxAccepts, g.opt.MaxRejects,
+		func(x kmer.Candidate) (bool, error) {
+			r := g.centroids[x.ID]
+
+			aln, err := pairwise.AlignOver(g.opt.Aligner, g.alpha, r.Seq.Seq, c.Seq.Seq)
+
+			if err != nil {
+				return false, err
+			}
+
+			v := aln.Identity()
+
+			if v < g.opt.Identity || !g.skewed(c, x.ID, aln) {
+				return false, nil
+			}
+
+			if best == nil || v > id {
+				best, id = r, v
+			}
+
+			return true, nil
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if best == nil {
+		g.index.Insert(c.Seq.Seq)
+		g.centroids = append(g.centroids, c)
+		g.sizes = append(g.sizes, c.Size)
+		return c, nil
+	}
+
+	best.Size += c.Size
+
+	for _, m := range c.Merged {
+		m.Identity = id * 100
+		best.Merged = append(best.Merged, m)
+	}
+
+	return best, nil
+}
+
+// skewed reports whether a cluster is skewed enough to join the centroid of an
+// ID by their alignment, or true if Options.UnoiseAlpha is not positive.
+func (g *Clusterer) skewed(c *cluster.Cluster, id int, aln *pairwise.Alignment) bool {
+	if g.opt.UnoiseAlpha <= 0 {
+		return true
+	}
+
+	skew := float64(c.Size) / float64(g.sizes[id])
+
+	return skew <= Beta(g.opt.UnoiseAlpha, aln.Distance())
+}
+
+// Centroids returns the clusters of the centroids in the order they were
+// added.
+func (g *Clusterer) Centroids() []*cluster.Cluster {
+	return g.centroids
+}
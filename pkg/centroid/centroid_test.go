@@ -0,0 +1,207 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package centroid_test
+
+import (
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/centroid"
+	"github.com/mys721tx/gsearch/pkg/cluster"
+)
+
+const (
+	seqA = "ACGTTGCAAGGCTTACCGATGCATGCAAGTCCTAGGATCA"
+	// seqB has 1 mismatch to seqA in 40 columns.
+	seqB = "ACGTTGCAAGGCTTACCGATCCATGCAAGTCCTAGGATCA"
+	// seqC has 4 mismatches to seqA in 40 columns.
+	seqC = "ACGTAGCAAGGCTAACCGATGCATGCTAGTCCTAGCATCA"
+)
+
+func newCluster(name, letters string) *cluster.Cluster {
+	return cluster.ParseAnno(
+		linear.NewSeq(name, []alphabet.Letter(letters), alphabet.DNA),
+	)
+}
+
+func TestNew(t *testing.T) {
+	for _, id := range []float64{-0.1, 1.1} {
+		_, err := centroid.New(centroid.Options{Identity: id})
+
+		assert.Error(t, err, "Identity out of range should return an error.")
+	}
+
+	_, err := centroid.New(centroid.Options{UnoiseAlpha: -1})
+
+	assert.Error(t, err, "Negative alpha should return an error.")
+}
+
+func TestBeta(t *testing.T) {
+	assert.Equal(t, 0.5, centroid.Beta(2, 0), "Beta of 0 should be 1/2.")
+	assert.Equal(t, 0.125, centroid.Beta(2, 1), "Beta of 1 should be 1/8.")
+	assert.Equal(t, 1.0/512, centroid.Beta(2, 4), "Beta of 4 should be 1/512.")
+}
+
+func TestClustererUnoise(t *testing.T) {
+	g, err := centroid.New(centroid.Options{UnoiseAlpha: centroid.DefaultUnoiseAlpha})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	a := newCluster("a;size=64", seqA)
+	b := newCluster("b;size=7", seqB)
+	x := newCluster("x;size=9", seqB)
+	c := newCluster("c;size=1", seqC)
+
+	for _, s := range []*cluster.Cluster{a, b, x, c} {
+		_, err := g.Add(s)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, []*cluster.Cluster{a, x, c}, g.Centroids(),
+		"Sequences above the skew of their distance should become centroids.",
+	)
+	assert.Equal(t, 71, a.Size, "Sizes of the members should be summed.")
+}
+
+func TestClusterer(t *testing.T) {
+	g, err := centroid.New(centroid.Options{Identity: centroid.DefaultIdentity})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	a := newCluster("a;size=5", seqA)
+	b := newCluster("b;size=3", seqB)
+	c := newCluster("c;size=2", seqC)
+	d := newCluster("d;size=1", seqA)
+
+	for _, x := range []*cluster.Cluster{a, b, c, d} {
+		_, err := g.Add(x)
+		assert.NoError(t, err)
+	}
+
+	if res := g.Centroids(); assert.Len(t, res, 2) {
+		assert.Equal(t, []*cluster.Cluster{a, c}, res,
+			"Sequences below the identity should become centroids.",
+		)
+	}
+
+	assert.Equal(t, 9, a.Size, "Sizes of the members should be summed.")
+
+	var ids []string
+	var identities []float64
+
+	for _, m := range a.Merged {
+		ids = append(ids, m.ID)
+		identities = append(identities, m.Identity)
+	}
+
+	assert.Equal(t, []string{"a;size=5", "b;size=3", "d;size=1"}, ids,
+		"Members should be kept in their order.",
+	)
+	assert.InDeltaSlice(t, []float64{100, 97.5, 100}, identities, 1e-9,
+		"Members should keep their identity to the centroid.",
+	)
+}
+
+func TestClustererProtein(t *testing.T) {
+	const (
+		protA = "MKVLWAALLVTFLAGCQAKVEQAVETEPEPELRQQTEWQS"
+		// protB has 1 substitution to protA in 40 columns.
+		protB = "MKVLWAALLVTFLAGCQAKVEQAVETEPEPELRQQAEWQS"
+		protC = "MSTNPKPQRKTKRNTNRRPQDVKFPGGGQIVGGVYLLPRR"
+	)
+
+	newProt := func(name, letters string) *cluster.Cluster {
+		return cluster.ParseAnno(
+			linear.NewSeq(name, []alphabet.Letter(letters), alphabet.Protein),
+		)
+	}
+
+	g, err := centroid.New(centroid.Options{
+		Identity: centroid.DefaultIdentity, Moltype: feat.Protein,
+	})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	a := newProt("a;size=3", protA)
+	b := newProt("b;size=2", protB)
+	c := newProt("c;size=1", protC)
+
+	for _, s := range []*cluster.Cluster{a, b, c} {
+		_, err := g.Add(s)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, []*cluster.Cluster{a, c}, g.Centroids(),
+		"Proteins should be clustered by their identity.",
+	)
+
+	g, err = centroid.New(centroid.Options{Identity: centroid.DefaultIdentity})
+
+	if assert.NoError(t, err) {
+		_, err := g.Add(newProt("a", protA))
+		assert.NoError(t, err, "The first sequence is not aligned.")
+
+		_, err = g.Add(newProt("b", protB))
+		assert.Error(t, err, "Proteins should not be aligned as DNA.")
+	}
+}
+
+func TestClustererMaxRejects(t *testing.T) {
+	// seqT shares more words with seqA than seqC does, but is less identical.
+	seqT := seqA[:20] + "GGGGGGGGGGGGGGGGGGGG"
+
+	for _, c := range []struct {
+		rejects int
+		n       int
+		msg     string
+	}{
+		{0, 2, "Sequences should join the accepted centroid after rejects."},
+		{1, 3, "Sequences should become centroids after the maximal rejects."},
+	} {
+		g, err := centroid.New(centroid.Options{
+			Identity:   0.85,
+			MaxAccepts: 1,
+			MaxRejects: c.rejects,
+		})
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		for _, x := range []*cluster.Cluster{
+			newCluster("t", seqT),
+			newCluster("c", seqC),
+			newCluster("a", seqA),
+		} {
+			_, err := g.Add(x)
+			assert.NoError(t, err)
+		}
+
+		assert.Len(t, g.Centroids(), c.n, c.msg)
+	}
+}
@@ -0,0 +1,476 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package chimera detects the chimeras of amplicons by the UCHIME model, as the
+// --uchime3_denovo and --uchime_ref commands of vsearch.
+//
+// A query is compared to its candidate parents, the sequences sharing the most
+// words with each of its Chunks chunks. Each pair of parents A and B models the
+// query as a chimera of A on the left and B on the right of a breakpoint. Each
+// position of the query votes for the model: yes if the query agrees only with
+// the parent of its side, no if it agrees only with the other parent, or
+// abstain if it agrees with neither. The positions where the query agrees with
+// both parents do not vote. The score of a side of Y yes, N no and A abstain
+// votes is
+//
+//	h = Y / (XN*(N + DN) + A)
+//
+// and the score of the model is the product of its sides, at the breakpoint of
+// the highest score.
+//
+// The Detector only compares DNA: the sequences are indexed by the words of
+// nucleotides and aligned by the nucleotide scores of pairwise. Check returns
+// an error for a query of any other molecule.
+package chimera
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/biogo/biogo/align"
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/seq"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+	"github.com/mys721tx/gsearch/pkg/kmer"
+	"github.com/mys721tx/gsearch/pkg/pairwise"
+	"github.com/mys721tx/gsearch/pkg/pool"
+)
+
+// The default options, as the --uchime3_denovo command of vsearch.
+const (
+	DefaultAbSkew   = 16
+	DefaultMinH     = 0.28
+	DefaultXN       = 8
+	DefaultDN       = 1.4
+	DefaultMinDiffs = 3
+	DefaultMinDiv   = 0.8
+)
+
+// Chunks is the number of chunks of a query searched for its parents.
+const Chunks = 4
+
+// DefaultCandidates is the default number of candidate parents of each chunk.
+const DefaultCandidates = 4
+
+// Options configures a Detector.
+type Options struct {
+	// AbSkew is the minimal abundance of a parent over the abundance of the
+	// query, or 0 for parents of any abundance.
+	AbSkew float64
+	// MinH is the minimal score of a chimera.
+	MinH float64
+	// XN is the weight of the no votes, and DN their pseudo-count.
+	XN, DN float64
+	// MinDiffs is the minimal number of yes votes on each side of a chimera.
+	MinDiffs int
+	// MinDiv is the minimal divergence of a chimera in percentage points: the
+	// identity of the query to the model minus its identity to the closest
+	// parent, IDQM - IDQT, as the --mindiv option of vsearch.
+	MinDiv float64
+	// Aligner aligns the query to the parents, or nil for the aligner of the
+	// default scores of pairwise.
+	Aligner align.Aligner
+	// K is the length of the words indexing the parents, or 0 for
+	// kmer.DefaultK.
+	K int
+	// Candidates is the number of candidate parents of each chunk, or 0 for
+	// DefaultCandidates.
+	Candidates int
+}
+
+// DefaultOptions returns the default options.
+func DefaultOptions() Options {
+	return Options{
+		AbSkew:   DefaultAbSkew,
+		MinH:     DefaultMinH,
+		XN:       DefaultXN,
+		DN:       DefaultDN,
+		MinDiffs: DefaultMinDiffs,
+		MinDiv:   DefaultMinDiv,
+	}
+}
+
+var errNegative = errors.New("chimera: options should not be negative")
+
+var errMolecule = errors.New("chimera: only dna is supported")
+
+// Verdict is the classification of a query.
+type Verdict int
+
+const (
+	// NonChimera is a query not explained by a chimera of its parents.
+	NonChimera Verdict = iota
+	// Chimera is a query explained by a chimera of its parents.
+	Chimera
+	// Borderline is a query whose score reaches Options.MinH, but whose
+	// model differs too little from its parents.
+	Borderline
+)
+
+// String returns the flag of the verdict in the uchimeout format: Y, N or ?.
+func (v Verdict) String() string {
+	switch v {
+	case Chimera:
+		return "Y"
+	case Borderline:
+		return "?"
+	}
+	return "N"
+}
+
+// Result is the best model of a query.
+type Result struct {
+	Query   *cluster.Cluster
+	Verdict Verdict
+	Score   float64
+	// A and B are the parents on the left and the right of the model, or nil
+	// if no model scores. T is the parent closest to the query, or nil if the
+	// query has no parent.
+	A, B, T *cluster.Cluster
+	// IDQM, IDQA, IDQB, IDAB and IDQT are the identities in percent of the
+	// query to the model, to A and to B, of A to B and of the query to T,
+	// over the positions of the query.
+	IDQM, IDQA, IDQB, IDAB, IDQT float64
+	// LY, LN and LA are the yes, no and abstain votes on the left, and RY,
+	// RN and RA on the right.
+	LY, LN, LA, RY, RN, RA int
+	// Div is the divergence in percentage points, IDQM minus IDQT.
+	Div float64
+}
+
+// Detector detects the chimeras of queries among its parents.
+//
+// De novo, the queries are added by Add, and the non-chimeric queries become
+// the parents of the next queries. Against a reference, the parents are added
+// by AddParent, and the queries are checked by Check or Run. AddParent must not
+// be called concurrently with other methods. Check and Run are safe for
+// concurrent use.
+type Detector struct {
+	opt     Options
+	index   *kmer.Index
+	parents []*cluster.Cluster
+}
+
+// New returns a Detector without any parent.
+func New(opt Options) (*Detector, error) {
+	if opt.AbSkew < 0 || opt.MinH < 0 || opt.XN < 0 || opt.DN < 0 ||
+		opt.MinDiffs < 0 || opt.MinDiv < 0 || opt.Candidates < 0 {
+		return nil, errNegative
+	}
+
+	if opt.Aligner == nil {
+		opt.Aligner = pairwise.NewAligner(
+			pairwise.Match, pairwise.Mismatch, pairwise.Gap, pairwise.GapOpen,
+		)
+	}
+
+	if opt.K == 0 {
+		opt.K = kmer.DefaultK
+	}
+
+	if opt.Candidates == 0 {
+		opt.Candidates = DefaultCandidates
+	}
+
+	ix, err := kmer.NewIndex(opt.K)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Detector{opt: opt, index: ix}, nil
+}
+
+// AddParent adds a parent to the Detector. The parent is expected to be DNA,
+// as the queries of Check.
+func (d *Detector) AddParent(c *cluster.Cluster) {
+	d.index.Insert(c.Seq.Seq)
+	d.parents = append(d.parents, c)
+}
+
+// Add detects the chimera of a query de novo, and adds the query as a parent
+// if it is not a chimera or borderline. The queries are expected in the order
+// of decreasing abundance, as sorted by cluster.ByAbundance.
+func (d *Detector) Add(q *cluster.Cluster) (*Result, error) {
+	r, err := d.Check(q)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Verdict == NonChimera {
+		d.AddParent(q)
+	}
+
+	return r, nil
+}
+
+// Check detects the chimera of a query among the parents at least
+// Options.AbSkew times as abundant as the query. Against a reference, the
+// AbSkew of 0 checks all the parents.
+//
+// If the query is not DNA, Check returns an error.
+func (d *Detector) Check(q *cluster.Cluster) (*Result, error) {
+	if q.Alpha == nil || q.Alpha.Moltype() != feat.DNA {
+		return nil, errMolecule
+	}
+
+	r := &Result{Query: q}
+
+	cands := d.candidates(q)
+
+	if len(cands) == 0 {
+		return r, nil
+	}
+
+	rows := make([]alphabet.Letters, len(cands))
+
+	for i, p := range cands {
+		row, err := project(d.opt.Aligner, p.Seq.Seq, q.Seq.Seq)
+
+		if err != nil {
+			return nil, err
+		}
+
+		rows[i] = row
+
+		if id := identity(q.Seq.Seq, row); r.T == nil || id > r.IDQT {
+			r.T, r.IDQT = p, id
+		}
+	}
+
+	var best model
+
+	for i := range cands {
+		for j := range cands {
+			if i != j {
+				d.fit(q.Seq.Seq, rows[i], rows[j], i, j, &best)
+			}
+		}
+	}
+
+	if best.score <= 0 {
+		return r, nil
+	}
+
+	a, b := rows[best.a], rows[best.b]
+	m := append(append(alphabet.Letters(nil), a[:best.at]...), b[best.at:]...)
+
+	r.Score = best.score
+	r.A, r.B = cands[best.a], cands[best.b]
+	r.IDQM = identity(q.Seq.Seq, m)
+	r.IDQA = identity(q.Seq.Seq, a)
+	r.IDQB = identity(q.Seq.Seq, b)
+	r.IDAB = identity(a, b)
+	r.LY, r.LN, r.LA = best.left[yes], best.left[no], best.left[abstain]
+	r.RY, r.RN, r.RA = best.right[yes], best.right[no], best.right[abstain]
+	r.Div = r.IDQM - r.IDQT
+
+	switch {
+	case r.Score < d.opt.MinH:
+	case r.LY >= d.opt.MinDiffs && r.RY >= d.opt.MinDiffs && r.Div >= d.opt.MinDiv:
+		r.Verdict = Chimera
+	default:
+		r.Verdict = Borderline
+	}
+
+	return r, nil
+}
+
+// Run checks the queries from a channel against the parents on threads
+// workers and calls out on their results in the order of the input. Run
+// returns the first error of Check or out.
+func (d *Detector) Run(ctx context.Context, in <-chan seq.Sequence, threads int, out func(*Result) error) error {
+	return pool.Ordered(
+		ctx, threads, in,
+		func(s seq.Sequence) (interface{}, error) {
+			q := cluster.ParseAnno(s)
+
+			r, err := d.Check(q)
+
+			if err != nil {
+				return nil, fmt.Errorf("chimera: query %q: %w", q.ID, err)
+			}
+
+			return r, nil
+		},
+		func(v interface{}) error {
+			return out(v.(*Result))
+		},
+	)
+}
+
+// candidates returns the parents sharing the most words with each chunk of a
+// query, up to Options.Candidates of each chunk, among the parents at least
+// Options.AbSkew times as abundant as the query.
+func (d *Detector) candidates(q *cluster.Cluster) []*cluster.Cluster {
+	var res []*cluster.Cluster
+
+	seen := make(map[int]bool)
+	l := q.Seq.Seq
+
+	for i := 0; i < Chunks; i++ {
+		chunk := l[i*len(l)/Chunks : (i+1)*len(l)/Chunks]
+		n := 0
+
+		for _, x := range d.index.Candidates(chunk) {
+			if n >= d.opt.Candidates {
+				break
+			}
+
+			p := d.parents[x.ID]
+
+			if float64(p.Size) < d.opt.AbSkew*float64(q.Size) {
+				continue
+			}
+
+			n++
+
+			if !seen[x.ID] {
+				seen[x.ID] = true
+				res = append(res, p)
+			}
+		}
+	}
+
+	return res
+}
+
+// project aligns a query to a parent and returns the letter of the parent at
+// each position of the query, or a gap if the parent has none. The letters of
+// the parent inserted between the positions of the query are dropped.
+func project(aligner align.Aligner, parent, query alphabet.Letters) (alphabet.Letters, error) {
+	a, err := pairwise.Align(aligner, parent, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	gap := pairwise.Alphabet.Gap()
+	row := make(alphabet.Letters, 0, len(query))
+
+	for i, v := range a.Query {
+		if v != gap {
+			row = append(row, a.Target[i])
+		}
+	}
+
+	return row, nil
+}
+
+// index maps the letters of pairwise.Alphabet regardless of their case.
+var index = pairwise.Alphabet.LetterIndex()
+
+// same reports whether two letters are the same letter of pairwise.Alphabet.
+func same(x, y alphabet.Letter) bool {
+	return index[x] >= 0 && index[x] == index[y]
+}
+
+// identity returns the identity in percent of two rows of the same length.
+func identity(x, y alphabet.Letters) float64 {
+	if len(x) == 0 {
+		return 0
+	}
+
+	n := 0
+
+	for i := range x {
+		if same(x[i], y[i]) {
+			n++
+		}
+	}
+
+	return 100 * float64(n) / float64(len(x))
+}
+
+// The votes of a position for the parent of its side.
+const (
+	yes = iota
+	no
+	abstain
+	votes
+)
+
+// model is the chimera of rows a and b at breakpoint at, whose votes are left
+// and right.
+type model struct {
+	a, b, at    int
+	score       float64
+	left, right [votes]int
+}
+
+// fit fits the query to the chimeras of rows a and b, and keeps the model of
+// the highest score in best. The first model is kept among ties.
+func (d *Detector) fit(q, a, b alphabet.Letters, i, j int, best *model) {
+	// kind holds the vote of each position for a, or -1 if it does not vote.
+	kind := make([]int, len(q))
+
+	var total [votes]int
+
+	for k := range q {
+		inA, inB := same(q[k], a[k]), same(q[k], b[k])
+
+		switch {
+		case inA && inB:
+			kind[k] = -1
+			continue
+		case inA:
+			kind[k] = yes
+		case inB:
+			kind[k] = no
+		default:
+			kind[k] = abstain
+		}
+
+		total[kind[k]]++
+	}
+
+	var left [votes]int
+
+	for at := 0; at <= len(q); at++ {
+		if at > 0 && kind[at-1] >= 0 {
+			left[kind[at-1]]++
+		}
+
+		// The votes for a on the right are the votes against b.
+		right := [votes]int{
+			yes:     total[no] - left[no],
+			no:      total[yes] - left[yes],
+			abstain: total[abstain] - left[abstain],
+		}
+
+		s := d.h(left) * d.h(right)
+
+		if s > best.score {
+			*best = model{a: i, b: j, at: at, score: s, left: left, right: right}
+		}
+	}
+}
+
+// h returns the score of the votes of a side, or 0 if the votes cannot be
+// weighted.
+func (d *Detector) h(v [votes]int) float64 {
+	w := d.opt.XN*(float64(v[no])+d.opt.DN) + float64(v[abstain])
+
+	if w == 0 {
+		return 0
+	}
+
+	return float64(v[yes]) / w
+}
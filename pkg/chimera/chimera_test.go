@@ -0,0 +1,212 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package chimera_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/chimera"
+	"github.com/mys721tx/gsearch/pkg/cluster"
+)
+
+// seqA and seqB are parents differing at every sixth position.
+const (
+	seqA = "GCTAAAGACAATTACATAACATACACGTCAGCACGAAACTTGTTGGCCCAGTGTGAATC" +
+		"GCTTAAGGGTTAAGTAAGTGTGATGCATACGCCTTTACTTGCTGTGTCCACCCCATCGGAC"
+	seqB = "TCTAAATACAATAACATACCATACCCGTCATCACGACACTTGATGGCCGAGTGTTAATC" +
+		"GGTTAAGTGTTAATTAAGTTTGATGGATACGGCTTTAGTTGCTTTGTCCCCCCCAACGGAC"
+)
+
+// newCluster returns a cluster of a sequence and its size.
+func newCluster(id, s string, size int) *cluster.Cluster {
+	return &cluster.Cluster{
+		Seq:  *linear.NewSeq(id, []alphabet.Letter(s), alphabet.DNAredundant),
+		Size: size,
+	}
+}
+
+func TestNew(t *testing.T) {
+	opt := chimera.DefaultOptions()
+	opt.XN = -1
+
+	_, err := chimera.New(opt)
+
+	assert.Error(t, err, "Negative option should return an error.")
+
+	opt = chimera.DefaultOptions()
+	opt.K = 13
+
+	_, err = chimera.New(opt)
+
+	assert.Error(t, err, "Illegal word length should return an error.")
+}
+
+func TestCheckMolecule(t *testing.T) {
+	d, err := chimera.New(chimera.DefaultOptions())
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	p := newCluster("p", "MKTAYIAKQR", 1)
+	p.Alpha = alphabet.Protein
+
+	_, err = d.Check(p)
+
+	assert.Error(t, err, "Proteins should return an error.")
+}
+
+func TestDetector(t *testing.T) {
+	d, err := chimera.New(chimera.DefaultOptions())
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	a := newCluster("a", seqA, 100)
+	b := newCluster("b", seqB, 100)
+	q := newCluster("q", seqA[:60]+seqB[60:], 5)
+
+	for _, c := range []*cluster.Cluster{a, b} {
+		r, err := d.Add(c)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, chimera.NonChimera, r.Verdict,
+				"Parent should not be a chimera.",
+			)
+		}
+	}
+
+	r, err := d.Add(q)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, chimera.Chimera, r.Verdict,
+			"Query should be a chimera of the parents.",
+		)
+		assert.Equal(t, a, r.A, "A should be the parent on the left.")
+		assert.Equal(t, b, r.B, "B should be the parent on the right.")
+		assert.Equal(t, 100.0, r.IDQM, "Model should be the query.")
+		assert.Equal(t, 10, r.LY, "Left should vote for A at its diffs.")
+		assert.Equal(t, 10, r.RY, "Right should vote for B at its diffs.")
+		assert.Zero(t, r.LN+r.LA+r.RN+r.RA, "No position should vote against.")
+		assert.InDelta(t, r.IDQM-r.IDQT, r.Div, 1e-9,
+			"Divergence should be the identity over the closest parent.",
+		)
+	}
+
+	m := newCluster("m", seqA[:30]+"T"+seqA[31:], 5)
+
+	r, err = d.Add(m)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, chimera.NonChimera, r.Verdict,
+			"Mutant of a parent should not be a chimera.",
+		)
+		assert.Equal(t, a, r.T, "Closest parent should be the parent.")
+	}
+
+	r, err = d.Add(newCluster("s", seqA[:60]+seqB[60:], 10))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, chimera.NonChimera, r.Verdict,
+			"Parents less abundant than AbSkew times should be skipped.",
+		)
+		assert.Nil(t, r.A, "Query without parents should not have a model.")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	b := new(bytes.Buffer)
+	w := chimera.NewWriter(b)
+
+	q := newCluster("q", "ACGT", 1)
+	a := newCluster("a", "ACGG", 20)
+	p := newCluster("b", "TCGT", 30)
+
+	err := w.Write(&chimera.Result{
+		Query: q, Verdict: chimera.Chimera, Score: 0.5,
+		A: a, B: p, T: p,
+		IDQM: 100, IDQA: 75, IDQB: 75, IDAB: 50, IDQT: 75,
+		LY: 1, RY: 1, Div: 25,
+	})
+
+	assert.NoError(t, err)
+
+	err = w.Write(&chimera.Result{Query: q})
+
+	assert.NoError(t, err)
+
+	assert.Equal(
+		t,
+		"0.5000\tq;size=1\ta;size=20\tb;size=30\tb;size=30\t100.0\t75.0\t75.0\t50.0\t75.0\t1\t0\t0\t1\t0\t0\t25.0\tY\n"+
+			"0.0000\tq;size=1\t*\t*\t*\t*\t*\t*\t*\t*\t0\t0\t0\t0\t0\t0\t*\tN\n",
+		b.String(),
+		"Results should be written in the uchimeout format.",
+	)
+}
+
+func TestRun(t *testing.T) {
+	opt := chimera.DefaultOptions()
+	opt.AbSkew = 0
+
+	d, err := chimera.New(opt)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	d.AddParent(newCluster("a", seqA, 1))
+	d.AddParent(newCluster("b", seqB, 1))
+
+	in := make(chan seq.Sequence, 3)
+
+	in <- linear.NewSeq("q;size=2", []alphabet.Letter(seqB[:50]+seqA[50:]), alphabet.DNAredundant)
+	in <- linear.NewSeq("a", []alphabet.Letter(seqA), alphabet.DNAredundant)
+	in <- linear.NewSeq("r", []alphabet.Letter(seqA[:90]+seqB[90:]), alphabet.DNAredundant)
+
+	close(in)
+
+	var res []*chimera.Result
+
+	err = d.Run(context.Background(), in, 2, func(r *chimera.Result) error {
+		res = append(res, r)
+		return nil
+	})
+
+	if assert.NoError(t, err) && assert.Len(t, res, 3) {
+		assert.Equal(t, "q", res[0].Query.ID, "Results should be in input order.")
+		assert.Equal(t, 2, res[0].Query.Size, "Query should be parsed.")
+		assert.Equal(t, chimera.Chimera, res[0].Verdict,
+			"Chimera of the reference should be detected.",
+		)
+		assert.Equal(t, "b", res[0].A.ID, "A should be the parent on the left.")
+		assert.Equal(t, chimera.NonChimera, res[1].Verdict,
+			"Reference should not be a chimera.",
+		)
+		assert.Equal(t, chimera.Chimera, res[2].Verdict,
+			"Chimera of the reference should be detected.",
+		)
+	}
+}
@@ -0,0 +1,73 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package chimera
+
+import (
+	"fmt"
+	"io"
+)
+
+// Writer writes results in the uchimeout format of vsearch: a line of 18
+// tab-separated fields of each query.
+//
+//	score	the score of the model
+//	Q	the query
+//	A, B	the parents on the left and the right of the model
+//	T	the parent closest to the query
+//	idQM	the identity of the query to the model
+//	idQA	the identity of the query to A
+//	idQB	the identity of the query to B
+//	idAB	the identity of A to B
+//	idQT	the identity of the query to T
+//	LY, LN, LA	the yes, no and abstain votes on the left
+//	RY, RN, RA	the yes, no and abstain votes on the right
+//	div	the divergence, idQM minus idQT
+//	YN	the verdict, Y, N or ?
+//
+// The fields of a query without a model are "*".
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter returns a Writer writing to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes the result of a query.
+func (w *Writer) Write(r *Result) error {
+	var err error
+
+	if r.A == nil {
+		_, err = fmt.Fprintf(
+			w.w, "%.4f\t%s\t*\t*\t*\t*\t*\t*\t*\t*\t0\t0\t0\t0\t0\t0\t*\t%v\n",
+			r.Score, r.Query.Name(), r.Verdict,
+		)
+		return err
+	}
+
+	_, err = fmt.Fprintf(
+		w.w,
+		"%.4f\t%s\t%s\t%s\t%s\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t%d\t%d\t%d\t%d\t%d\t%d\t%.1f\t%v\n",
+		r.Score, r.Query.Name(), r.A.Name(), r.B.Name(), r.T.Name(),
+		r.IDQM, r.IDQA, r.IDQB, r.IDAB, r.IDQT,
+		r.LY, r.LN, r.LA, r.RY, r.RN, r.RA,
+		r.Div, r.Verdict,
+	)
+
+	return err
+}
@@ -0,0 +1,211 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Attr is a field of a FASTA header other than the name and the size.
+//
+// A key-value pair such as "sample=A" has both Key and Value. A monad such as
+// "HBB" has only Value.
+type Attr struct {
+	Key, Value string
+}
+
+// String returns the attribute as it is written in a FASTA header.
+func (a Attr) String() string {
+	if a.Key == "" {
+		return a.Value
+	}
+	return a.Key + "=" + a.Value
+}
+
+// Policy is the way to merge an attribute of duplicated sequences.
+type Policy int
+
+const (
+	// KeepFirst keeps the first value of the attribute.
+	KeepFirst Policy = iota
+	// Concat joins the distinct values of the attribute by commas.
+	Concat
+	// Sum adds the values of the attribute if all of them are numbers, or
+	// keeps the first value otherwise.
+	Sum
+	// Drop removes the attribute unless every sequence has the same value.
+	Drop
+)
+
+// String returns the name of the policy.
+func (p Policy) String() string {
+	switch p {
+	case KeepFirst:
+		return "keep-first"
+	case Concat:
+		return "concat"
+	case Sum:
+		return "sum"
+	case Drop:
+		return "drop"
+	}
+	return fmt.Sprintf("Policy(%d)", int(p))
+}
+
+// ParsePolicy parses the name of a policy.
+func ParsePolicy(name string) (Policy, error) {
+	switch name {
+	case "keep-first", "first":
+		return KeepFirst, nil
+	case "concat", "concatenate":
+		return Concat, nil
+	case "sum":
+		return Sum, nil
+	case "drop":
+		return Drop, nil
+	}
+	return KeepFirst, fmt.Errorf("unknown policy %q", name)
+}
+
+// DefaultKey is the key in Policies for attributes without their own policy.
+const DefaultKey = "*"
+
+// Policies maps the key of attributes to their merge policy.
+//
+// Attributes whose key is not in Policies use the policy of DefaultKey, or
+// KeepFirst if DefaultKey is also absent. Monads are always kept from the
+// first sequence.
+type Policies map[string]Policy
+
+// Of returns the policy of a key.
+func (p Policies) Of(key string) Policy {
+	if v, prs := p[key]; prs {
+		return v
+	}
+	return p[DefaultKey]
+}
+
+// ParsePolicies parses a comma separated list of key=policy, such as
+// "*=drop,sample=concat,count=sum".
+func ParsePolicies(s string) (Policies, error) {
+	p := make(Policies)
+
+	for _, item := range strings.Split(s, ",") {
+		if item == "" {
+			continue
+		}
+
+		pair := strings.Split(item, "=")
+
+		if len(pair) != 2 || pair[0] == "" {
+			return nil, fmt.Errorf("malformed policy %q", item)
+		}
+
+		v, err := ParsePolicy(pair[1])
+
+		if err != nil {
+			return nil, err
+		}
+
+		p[pair[0]] = v
+	}
+
+	return p, nil
+}
+
+// indexAttr returns the index of the key-value pair with the key in attrs, or
+// -1 if it is absent.
+func indexAttr(attrs []Attr, key string) int {
+	for i, a := range attrs {
+		if a.Key != "" && a.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// mergeAttrs merges the key-value pairs of o into attrs by the policies and
+// returns the merged attributes.
+func mergeAttrs(attrs, o []Attr, p Policies) []Attr {
+	kept := attrs[:0]
+
+	for _, a := range attrs {
+		// Pairs missing from o are conflicts under Drop.
+		if a.Key != "" && p.Of(a.Key) == Drop {
+			if i := indexAttr(o, a.Key); i < 0 || o[i].Value != a.Value {
+				continue
+			}
+		}
+		kept = append(kept, a)
+	}
+
+	attrs = kept
+
+	for _, b := range o {
+		if b.Key == "" {
+			continue
+		}
+
+		i := indexAttr(attrs, b.Key)
+
+		switch pol := p.Of(b.Key); {
+		case i < 0 && pol == Drop:
+			// Absent from the first sequence, thus a conflict.
+		case i < 0:
+			attrs = append(attrs, b)
+		case pol == Concat:
+			attrs[i].Value = concatValue(attrs[i].Value, b.Value)
+		case pol == Sum:
+			attrs[i].Value = sumValue(attrs[i].Value, b.Value)
+		}
+	}
+
+	return attrs
+}
+
+// concatValue appends each item of the comma separated list v to the list l if
+// it is not in l, so merging lists already concatenated gives the same list.
+func concatValue(l, v string) string {
+	items := strings.Split(l, ",")
+
+next:
+	for _, x := range strings.Split(v, ",") {
+		for _, item := range items {
+			if item == x {
+				continue next
+			}
+		}
+
+		items = append(items, x)
+	}
+
+	return strings.Join(items, ",")
+}
+
+// sumValue adds two numbers, or returns a if either is not a number.
+func sumValue(a, b string) string {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+
+	if errA != nil || errB != nil {
+		return a
+	}
+
+	return strconv.FormatFloat(x+y, 'f', -1, 64)
+}
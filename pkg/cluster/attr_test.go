@@ -0,0 +1,142 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cluster_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+)
+
+func TestAttrString(t *testing.T) {
+	assert.Equal(t, "sample=A", cluster.Attr{"sample", "A"}.String(),
+		"A key-value pair should be joined by an equal sign.",
+	)
+	assert.Equal(t, "HBB", cluster.Attr{Value: "HBB"}.String(),
+		"A monad should be its value.",
+	)
+}
+
+func TestParsePolicies(t *testing.T) {
+	p, err := cluster.ParsePolicies("*=drop,sample=concat,count=sum")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, cluster.Concat, p.Of("sample"),
+			"Key should use its own policy.",
+		)
+		assert.Equal(t, cluster.Sum, p.Of("count"),
+			"Key should use its own policy.",
+		)
+		assert.Equal(t, cluster.Drop, p.Of("organism"),
+			"Key without a policy should use the default.",
+		)
+	}
+
+	p, err = cluster.ParsePolicies("")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, cluster.KeepFirst, p.Of("sample"),
+			"Default policy should be keep-first.",
+		)
+	}
+
+	for _, s := range []string{"sample", "=sum", "sample=max"} {
+		_, err := cluster.ParsePolicies(s)
+
+		assert.Error(t, err, "Malformed policy should return an error.")
+	}
+}
+
+// mergeAttrs merges clusters with the given attributes by p.
+func mergeAttrs(p cluster.Policies, attrs ...[]cluster.Attr) []cluster.Attr {
+	c := cluster.Cluster{Attrs: attrs[0]}
+
+	for _, a := range attrs[1:] {
+		c.Merge(&cluster.Cluster{Attrs: a}, p)
+	}
+
+	return c.Attrs
+}
+
+func TestMergeKeepFirst(t *testing.T) {
+	res := mergeAttrs(
+		cluster.Policies{},
+		[]cluster.Attr{{"sample", "A"}, {Value: "HBB"}},
+		[]cluster.Attr{{"sample", "B"}, {"organism", "9606"}, {Value: "HBA"}},
+	)
+
+	assert.Equal(
+		t,
+		[]cluster.Attr{{"sample", "A"}, {Value: "HBB"}, {"organism", "9606"}},
+		res,
+		"First values should be kept and new keys appended.",
+	)
+}
+
+func TestMergeConcat(t *testing.T) {
+	res := mergeAttrs(
+		cluster.Policies{"sample": cluster.Concat},
+		[]cluster.Attr{{"sample", "A"}},
+		[]cluster.Attr{{"sample", "B"}},
+		[]cluster.Attr{{"sample", "A"}},
+	)
+
+	assert.Equal(t, []cluster.Attr{{"sample", "A,B"}}, res,
+		"Distinct values should be joined by commas.",
+	)
+}
+
+func TestMergeConcatList(t *testing.T) {
+	res := mergeAttrs(
+		cluster.Policies{"sample": cluster.Concat},
+		[]cluster.Attr{{"sample", "A,C"}},
+		[]cluster.Attr{{"sample", "B,A"}},
+	)
+
+	assert.Equal(t, []cluster.Attr{{"sample", "A,C,B"}}, res,
+		"Values already joined should be joined by their items.",
+	)
+}
+
+func TestMergeSum(t *testing.T) {
+	res := mergeAttrs(
+		cluster.Policies{cluster.DefaultKey: cluster.Sum},
+		[]cluster.Attr{{"count", "2"}, {"ee", "0.5"}, {"sample", "A"}},
+		[]cluster.Attr{{"count", "3"}, {"ee", "0.25"}, {"sample", "B"}},
+	)
+
+	assert.Equal(
+		t,
+		[]cluster.Attr{{"count", "5"}, {"ee", "0.75"}, {"sample", "A"}},
+		res,
+		"Numbers should be summed and others keep the first value.",
+	)
+}
+
+func TestMergeDrop(t *testing.T) {
+	res := mergeAttrs(
+		cluster.Policies{cluster.DefaultKey: cluster.Drop},
+		[]cluster.Attr{{"sample", "A"}, {"organism", "9606"}, {"run", "1"}},
+		[]cluster.Attr{{"sample", "B"}, {"organism", "9606"}, {"lane", "2"}},
+	)
+
+	assert.Equal(t, []cluster.Attr{{"organism", "9606"}}, res,
+		"Only the values shared by all sequences should be kept.",
+	)
+}
@@ -18,10 +18,12 @@
 package cluster
 
 import (
+	"bytes"
 	"fmt"
 	"strconv"
 	"strings"
 
+	"github.com/biogo/biogo/alphabet"
 	"github.com/biogo/biogo/seq"
 	"github.com/biogo/biogo/seq/linear"
 )
@@ -34,15 +36,98 @@ const (
 )
 
 // Cluster is a struct that stores the name and size of an FASTA annotation.
+//
+// Attrs holds the other fields of the FASTA header in their order. Qual holds
+// the quality of each letter when the sequence is read from FASTQ, and is nil
+// otherwise. Merged holds the members of the merged sequences, whose Strand is
+// seq.Minus if the sequence was merged in reverse orientation.
 type Cluster struct {
 	linear.Seq
+	Attrs  []Attr
+	Qual   []alphabet.Qphred
+	Encode alphabet.Encoding
 	Size   int
-	Merged []*seq.Annotation
+	Merged []*Member
+}
+
+// Member is a sequence merged into a cluster.
+type Member struct {
+	// Annotation is the annotation of the sequence as read.
+	*seq.Annotation
+	// Len is the length of the sequence.
+	Len int
+	// Identity is the percent identity of the sequence to the cluster.
+	Identity float64
 }
 
-// Name returns the ID and the size of the cluster.
+// Name returns the ID, the attributes and the size of the cluster.
 func (c *Cluster) Name() string {
-	return fmt.Sprintf("%v;size=%d", c.ID, c.Size)
+	var b strings.Builder
+
+	b.WriteString(c.ID)
+
+	for _, a := range c.Attrs {
+		b.WriteByte(';')
+		b.WriteString(a.String())
+	}
+
+	fmt.Fprintf(&b, ";size=%d", c.Size)
+
+	return b.String()
+}
+
+// Merge merges another cluster of the same sequence into the cluster.
+//
+// The sizes are summed, the members of o are appended to Merged, the
+// qualities are merged by MergeQual and the attributes are merged by p.
+func (c *Cluster) Merge(o *Cluster, p Policies) {
+	c.Size += o.Size
+	c.Merged = append(c.Merged, o.Merged...)
+	c.MergeQual(o)
+	c.Attrs = mergeAttrs(c.Attrs, o.Attrs, p)
+}
+
+// At returns the letter and its quality at position i.
+func (c *Cluster) At(i int) alphabet.QLetter {
+	l := c.Seq.At(i)
+
+	if c.Qual != nil {
+		l.Q = c.Qual[i]
+	}
+
+	return l
+}
+
+// Encoding returns the quality encoding of the cluster.
+func (c *Cluster) Encoding() alphabet.Encoding {
+	return c.Encode
+}
+
+// RevComp reverse complements the sequence and reverses its quality.
+func (c *Cluster) RevComp() {
+	c.Seq.RevComp()
+
+	for i, j := 0, len(c.Qual)-1; i < j; i, j = i+1, j-1 {
+		c.Qual[i], c.Qual[j] = c.Qual[j], c.Qual[i]
+	}
+}
+
+// MergeQual merges the quality of another cluster of the same sequence, or of
+// a prefix of the sequence.
+//
+// Each position keeps the highest quality of the two clusters, as the
+// --fastq_qout_max option of vsearch. If either cluster has no quality, the
+// quality is left unchanged.
+func (c *Cluster) MergeQual(o *Cluster) {
+	if c.Qual == nil || o.Qual == nil || len(o.Qual) > len(c.Qual) {
+		return
+	}
+
+	for i, q := range o.Qual {
+		if q > c.Qual[i] {
+			c.Qual[i] = q
+		}
+	}
 }
 
 // PassFilter checks if the cluster can pass filter of given size.
@@ -73,7 +158,7 @@ func (c ByAbundance) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
 //
 // The higher abundance cluster is in front of the lower abundance sequence.
 // When two clusters have same abundance, they are sorted by the lexicographical
-//order of the labels.
+// order of the labels.
 func (c ByAbundance) Less(i, j int) bool {
 	if c[i].Size > c[j].Size {
 		return true
@@ -85,6 +170,61 @@ func (c ByAbundance) Less(i, j int) bool {
 	return false
 }
 
+// ByLength implements methods to sort a slice of a cluster by length.
+type ByLength []*Cluster
+
+// Len returns the length of a ByLength.
+func (c ByLength) Len() int { return len(c) }
+
+// Swap swaps two elements in a ByLength.
+func (c ByLength) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+
+// Less establishes the order between two clusters when sort ByLength.
+//
+// The longer cluster is in front of the shorter cluster. When two clusters
+// have same length, the higher abundance cluster is in front, as the
+// --sortbylength command of vsearch.
+func (c ByLength) Less(i, j int) bool {
+	if c[i].Len() != c[j].Len() {
+		return c[i].Len() > c[j].Len()
+	}
+	return c[i].Size > c[j].Size
+}
+
+// BySequence implements methods to sort a slice of a cluster by sequence.
+type BySequence []*Cluster
+
+// Len returns the length of a BySequence.
+func (c BySequence) Len() int { return len(c) }
+
+// Swap swaps two elements in a BySequence.
+func (c BySequence) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+
+// Less establishes the order between two clusters when sort BySequence.
+//
+// The clusters are sorted by the lexicographical order of their letters.
+func (c BySequence) Less(i, j int) bool {
+	a := alphabet.LettersToBytes(c[i].Seq.Seq)
+	b := alphabet.LettersToBytes(c[j].Seq.Seq)
+	return bytes.Compare(a, b) < 0
+}
+
+// letters returns the letters of a sequence, without copying those of a
+// linear.Seq.
+func letters(s seq.Sequence) alphabet.Letters {
+	if l, ok := s.(*linear.Seq); ok {
+		return l.Seq
+	}
+
+	l := make(alphabet.Letters, s.Len())
+
+	for i := range l {
+		l[i] = s.At(i).L
+	}
+
+	return l
+}
+
 // ParseAnno parses the annotation in sequence and returns it as a cluster.
 //
 // The first monad is used as the name of the sequence; otherwise defaults to
@@ -92,24 +232,58 @@ func (c ByAbundance) Less(i, j int) bool {
 //
 // The last key-value pair with key "size" is used as the size; otherwise
 // defaults to 1.
-func ParseAnno(s *linear.Seq) *Cluster {
+//
+// The other monads and key-value pairs are kept in Attrs in their order. The
+// last value is used if a key appears more than once.
+//
+// If s carries quality, such as a linear.QSeq read from FASTQ, the quality is
+// kept in the cluster.
+func ParseAnno(s seq.Sequence) *Cluster {
+	l := linear.Seq{Annotation: *s.CloneAnnotation(), Seq: letters(s)}
 
 	res := Cluster{
-		Seq:    *s,
-		Merged: []*seq.Annotation{&s.Annotation},
+		Seq: l,
+		Merged: []*Member{{
+			Annotation: s.CloneAnnotation(),
+			Len:        l.Len(),
+			Identity:   100,
+		}},
+	}
+
+	if q, ok := s.(seq.Scorer); ok {
+		res.Encode = q.Encoding()
+		res.Qual = make([]alphabet.Qphred, s.Len())
+
+		for i := range res.Qual {
+			res.Qual[i] = s.At(i).Q
+		}
 	}
 
 	var monads []string
 
 	pairs := make(map[string]string)
 
-	for _, item := range strings.Split(s.ID, ";") {
+	for _, item := range strings.Split(l.ID, ";") {
 		// If more than 2 then skip
 		switch pair := strings.Split(item, "="); len(pair) {
 		case 1:
+			if len(monads) > 0 && pair[0] != "" {
+				res.Attrs = append(res.Attrs, Attr{Value: pair[0]})
+			}
 			monads = append(monads, pair[0])
 		case 2:
 			pairs[pair[0]] = pair[1]
+
+			// Size is written by Name.
+			if pair[0] == "size" {
+				break
+			}
+
+			if i := indexAttr(res.Attrs, pair[0]); i < 0 {
+				res.Attrs = append(res.Attrs, Attr{pair[0], pair[1]})
+			} else {
+				res.Attrs[i].Value = pair[1]
+			}
 		}
 	}
 
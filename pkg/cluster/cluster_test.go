@@ -147,6 +147,67 @@ func TestParseAnnoMultipleMonads(t *testing.T) {
 	assert.Equal(t, res.Size, 100, "Size should be the value of size.")
 }
 
+func TestParseAnnoQuality(t *testing.T) {
+	seq := linear.NewQSeq(
+		"foo;size=100",
+		[]alphabet.QLetter{{L: 'A', Q: 10}, {L: 'T', Q: 0}},
+		alphabet.DNA,
+		seqio.Phred64,
+	)
+
+	res := cluster.ParseAnno(seq)
+
+	assert.Equal(t, res.ID, "foo", "Name should be the first monad.")
+	assert.Equal(t, "AT", res.String(), "Letters should be kept.")
+	assert.Equal(t, []alphabet.Qphred{10, 0}, res.Qual,
+		"Quality should be kept.",
+	)
+	assert.Equal(t, seqio.Phred64, res.Encoding(),
+		"Encoding should be kept.",
+	)
+	assert.Equal(t, alphabet.Qphred(10), res.At(0).Q,
+		"At should return the quality.",
+	)
+}
+
+func TestClusterMergeQual(t *testing.T) {
+	c := cluster.Cluster{Qual: []alphabet.Qphred{10, 30, 20}}
+	o := cluster.Cluster{Qual: []alphabet.Qphred{20, 10, 20}}
+
+	c.MergeQual(&o)
+
+	assert.Equal(t, []alphabet.Qphred{20, 30, 20}, c.Qual,
+		"The highest quality of each position should be kept.",
+	)
+
+	c.MergeQual(&cluster.Cluster{})
+
+	assert.Equal(t, []alphabet.Qphred{20, 30, 20}, c.Qual,
+		"Quality should not change when the other has no quality.",
+	)
+}
+
+func TestParseAnnoAttrs(t *testing.T) {
+	seq := linear.NewSeq(
+		"NM_000518;HBB;size=628;organism=9606;sample=A;sample=B",
+		[]alphabet.Letter("ATTC"),
+		alphabet.DNA,
+	)
+
+	res := cluster.ParseAnno(seq)
+
+	assert.Equal(
+		t,
+		[]cluster.Attr{{Value: "HBB"}, {"organism", "9606"}, {"sample", "B"}},
+		res.Attrs,
+		"Other fields should be kept in order with the last value.",
+	)
+	assert.Equal(t, "NM_000518;HBB;organism=9606;sample=B;size=628",
+		res.Name(),
+		"Name should write the attributes before the size.",
+	)
+}
+
 func BenchmarkParseAnno(b *testing.B) {
 	seq := linear.NewSeq(
 		"size=100;foo;spam=egg;bar",
@@ -304,3 +365,67 @@ func TestByAbundanceName(t *testing.T) {
 		)
 	}
 }
+
+func TestByLength(t *testing.T) {
+	clus := []*cluster.Cluster{
+		{
+			Seq:  *linear.NewSeq("A", []alphabet.Letter("AA"), alphabet.DNA),
+			Size: 100,
+		},
+		{
+			Seq:  *linear.NewSeq("B", []alphabet.Letter("AAAA"), alphabet.DNA),
+			Size: 1,
+		},
+		{
+			Seq:  *linear.NewSeq("C", []alphabet.Letter("AC"), alphabet.DNA),
+			Size: 200,
+		},
+		{
+			Seq:  *linear.NewSeq("D", []alphabet.Letter("AAA"), alphabet.DNA),
+			Size: 10,
+		},
+	}
+
+	expects := []string{"B", "D", "C", "A"}
+
+	sort.Sort(cluster.ByLength(clus))
+
+	for i, v := range expects {
+		assert.Equal(t, v, clus[i].ID,
+			"Clusters are sorted by their length, then by their abundance.",
+		)
+	}
+}
+
+func TestBySequence(t *testing.T) {
+	clus := []*cluster.Cluster{
+		{Seq: *linear.NewSeq("A", []alphabet.Letter("GA"), alphabet.DNA)},
+		{Seq: *linear.NewSeq("B", []alphabet.Letter("ACGT"), alphabet.DNA)},
+		{Seq: *linear.NewSeq("C", []alphabet.Letter("AC"), alphabet.DNA)},
+		{Seq: *linear.NewSeq("D", []alphabet.Letter("T"), alphabet.DNA)},
+	}
+
+	expects := []string{"C", "B", "A", "D"}
+
+	sort.Sort(cluster.BySequence(clus))
+
+	for i, v := range expects {
+		assert.Equal(t, v, clus[i].ID,
+			"Clusters are sorted by their letters in lexicographical order.",
+		)
+	}
+}
+
+func TestParseAnnoMember(t *testing.T) {
+	c := cluster.ParseAnno(
+		linear.NewSeq("foo;size=2", []alphabet.Letter("ACGT"), alphabet.DNA),
+	)
+
+	if assert.Len(t, c.Merged, 1) {
+		m := c.Merged[0]
+
+		assert.Equal(t, "foo;size=2", m.ID, "Member should keep the header.")
+		assert.Equal(t, 4, m.Len, "Member should keep the length.")
+		assert.Equal(t, 100.0, m.Identity, "Member should be identical.")
+	}
+}
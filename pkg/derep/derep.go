@@ -19,50 +19,221 @@
 package derep
 
 import (
+	"context"
+	"errors"
 	"io"
 	"log"
 	"sync"
 
-	"github.com/biogo/biogo/io/seqio/fasta"
+	"github.com/biogo/biogo/seq"
 	"github.com/biogo/biogo/seq/linear"
 
 	"github.com/mys721tx/gsearch/pkg/cluster"
+	"github.com/mys721tx/gsearch/pkg/relabel"
 	"github.com/mys721tx/gsearch/pkg/seqio"
+	"github.com/mys721tx/gsearch/pkg/uc"
 )
 
+// Options configures the dereplication of Run.
+type Options struct {
+	// Min and Max are the abundance filter passed to Cluster.PassFilter.
+	Min, Max int
+	// Policies merges the header attributes of duplicated sequences.
+	Policies cluster.Policies
+	// Strand is the strand compared by Key.
+	Strand Strand
+	// Mode is the way sequences are matched.
+	Mode Mode
+	// LowMemory keys the sequences by a 128-bit hash and stores them in 2
+	// bits per letter. The output is the same as the default.
+	LowMemory bool
+	// DropMerged discards the annotations of the merged sequences kept in
+	// Cluster.Merged.
+	DropMerged bool
+	// MaxMemory is the estimated memory in bytes held by the unique sequences
+	// before they are spilled to disk, or 0 to hold all of them in memory.
+	MaxMemory int64
+	// TempDir is the directory of the spilled sequences, or the default
+	// directory for temporary files if empty.
+	TempDir string
+	// Order is the order of the clusters written.
+	Order Order
+	// Relabel relabels the clusters written, in their order.
+	Relabel *relabel.Relabeler
+	// UC writes the members of the clusters written as UC records. It cannot
+	// be used with DropMerged or MaxMemory, which discard the members.
+	UC *uc.Writer
+	// Threads is the number of shards dereplicated in parallel. It is not
+	// used with MaxMemory.
+	Threads int
+}
+
 // DeRep receives a sequence from a channel and builds a map.
 //
 // If a sequence is in a map, DeRep parses the annotation and sums the size of
 // the new sequence with the cluster in the map. if not, DeRep adds a new
 // cluster into the map.
 //
-// After the channel in is closed, DeRep writes the map to a file.
+// After the channel in is closed, DeRep writes the clusters to a file, sorted
+// by cluster.ByAbundance.
 func DeRep(in <-chan *linear.Seq, f io.Writer, min, max int, wg *sync.WaitGroup) {
+	c := make(chan seq.Sequence)
+
+	go func() {
+		defer close(c)
+
+		for s := range in {
+			c <- s
+		}
+	}()
+
+	DeRepSeq(c, seqio.NewWriter(f, seqio.FASTA), min, max, wg)
+}
+
+// DeRepSeq dereplicates the sequences from a channel as DeRep, and writes the
+// clusters to a writer.
+//
+// The sequences can be any seq.Sequence, such as the linear.QSeq read from
+// FASTQ. When the sequences carry quality, the cluster keeps the highest
+// quality of each position. The header attributes keep the first value of each
+// key.
+//
+// If the writer has encountered any error, DeRepSeq will panic. Use Run to
+// handle the error instead.
+func DeRepSeq(in <-chan seq.Sequence, w seqio.Writer, min, max int, wg *sync.WaitGroup) {
 	defer wg.Done()
 
-	rep := make(map[string]*cluster.Cluster)
+	if err := Run(context.Background(), in, w, Options{Min: min, Max: max}); err != nil {
+		log.Panicf("Error occurred during write: %s", err)
+	}
+}
 
-	for s := range in {
-		c := cluster.ParseAnno(s)
+// Run dereplicates the sequences from a channel as DeRep and writes the
+// clusters to a writer in opt.Order. The header attributes are merged by
+// opt.Policies.
+//
+// With opt.Strand set to Both, a sequence is merged with its reverse
+// complement. The cluster keeps the orientation of its first sequence, and the
+// annotations of the sequences merged in reverse orientation are marked
+// seq.Minus in Merged.
+//
+// With opt.LowMemory set, the unique sequences are held by a hashTable
+// instead of a map of their letters. With opt.DropMerged set, the clusters do
+// not keep the annotations of their sequences.
+//
+// With opt.Mode set to Prefix, a sequence that is a prefix of longer sequences
+// is then merged into the longest of them, as mergePrefix.
+//
+// With opt.Threads larger than 1, the sequences are dereplicated in shards in
+// parallel, as runShards. The output is the same as a single thread.
+//
+// With opt.MaxMemory set, the unique sequences are spilled to disk when they
+// reach the budget, as runSpill.
+//
+// Run returns nil after in is closed and all clusters are written. If the
+// writer encounters an error, Run returns the error. If ctx is done before in
+// is closed, Run returns the error of ctx without writing anything.
+func Run(ctx context.Context, in <-chan seq.Sequence, w seqio.Writer, opt Options) error {
+	if opt.Mode == Prefix && opt.Strand != Plus {
+		return errPrefixStrand
+	}
+
+	if opt.UC != nil && (opt.DropMerged || opt.MaxMemory > 0) {
+		return errUCMerged
+	}
 
-		if _, prs := rep[s.String()]; !prs {
-			rep[s.String()] = c
-		} else {
-			rep[s.String()].Size += c.Size
-			rep[s.String()].Merged = append(
-				rep[s.String()].Merged,
-				c.Merged...,
-			)
+	if opt.MaxMemory > 0 {
+		return runSpill(ctx, in, w, opt)
+	}
+
+	var uniq []*cluster.Cluster
+	var err error
+
+	if opt.Threads > 1 {
+		uniq, err = runShards(ctx, in, opt)
+	} else {
+		uniq, err = collect(ctx, in, opt)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if opt.Mode == Prefix {
+		uniq = mergePrefix(uniq, opt.Policies)
+	}
+
+	sortClusters(uniq, opt.Order)
+
+	for _, c := range uniq {
+		if err := write(w, c, opt); err != nil {
+			return err
 		}
 	}
 
-	w := fasta.NewWriter(f, seqio.WidthCol)
+	return nil
+}
+
+// collect dereplicates the sequences from a channel into a table and returns
+// the clusters in the order of their first sequence.
+func collect(ctx context.Context, in <-chan seq.Sequence, opt Options) ([]*cluster.Cluster, error) {
+	t := newTable(opt)
+
+	err := receive(ctx, in, func(_ int64, c *cluster.Cluster) error {
+		if opt.DropMerged {
+			c.Merged = nil
+		}
+
+		t.add(c)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return t.clusters(), nil
+}
+
+// receive parses the sequences from a channel as clusters and calls f with
+// their index until in is closed. It returns the error of f, or the error of
+// ctx if ctx is done.
+func receive(ctx context.Context, in <-chan seq.Sequence, f func(int64, *cluster.Cluster) error) error {
+	for i := int64(0); ; i++ {
+		select {
+		case s, ok := <-in:
+			if !ok {
+				return nil
+			}
 
-	for _, s := range rep {
-		if s.PassFilter(min, max) {
-			if _, err := w.Write(s); err != nil {
-				log.Panicf("Error occurred during write: %s", err)
+			if err := f(i, cluster.ParseAnno(s)); err != nil {
+				return err
 			}
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 }
+
+// write relabels and writes a cluster if it passes the abundance filter, and
+// writes its members to opt.UC.
+func write(w seqio.Writer, c *cluster.Cluster, opt Options) error {
+	if !c.PassFilter(opt.Min, opt.Max) {
+		return nil
+	}
+
+	opt.Relabel.Cluster(c)
+
+	if _, err := w.Write(c); err != nil {
+		return err
+	}
+
+	if opt.UC != nil {
+		return opt.UC.Write(c)
+	}
+
+	return nil
+}
+
+var errUCMerged = errors.New("derep: UC output needs the merged members")
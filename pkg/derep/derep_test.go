@@ -18,12 +18,14 @@ package derep_test
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"sync"
 	"testing"
 
 	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
 	"github.com/biogo/biogo/seq/linear"
 
 	"github.com/stretchr/testify/assert"
@@ -33,7 +35,9 @@ import (
 
 	"github.com/mys721tx/gsearch/pkg/cluster"
 	"github.com/mys721tx/gsearch/pkg/derep"
+	"github.com/mys721tx/gsearch/pkg/relabel"
 	"github.com/mys721tx/gsearch/pkg/seqio"
+	"github.com/mys721tx/gsearch/pkg/uc"
 )
 
 var wg sync.WaitGroup
@@ -87,8 +91,87 @@ func TestDeRep(t *testing.T) {
 	assert.Equal(t, res.Size, 114, "Size should be the sum of all sizes.")
 }
 
+func TestRunPolicies(t *testing.T) {
+	seqs := []*linear.Seq{
+		linear.NewSeq(
+			"foo;sample=A;barcodelabel=AC;size=2",
+			[]alphabet.Letter("ATTC"),
+			alphabet.DNA,
+		),
+		linear.NewSeq(
+			"bar;sample=B;barcodelabel=GT;size=3",
+			[]alphabet.Letter("ATTC"),
+			alphabet.DNA,
+		),
+	}
+
+	c := make(chan seq.Sequence, len(seqs))
+
+	for _, s := range seqs {
+		c <- s
+	}
+
+	close(c)
+
+	w := new(bytes.Buffer)
+
+	err := derep.Run(
+		context.Background(), c, seqio.NewWriter(w, seqio.FASTA),
+		derep.Options{Policies: cluster.Policies{
+			"sample":           cluster.Concat,
+			cluster.DefaultKey: cluster.Drop,
+		}},
+	)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, ">foo;sample=A,B;size=5\nATTC\n", w.String(),
+			"Attributes should be merged by the policies.",
+		)
+	}
+}
+
+func TestDeRepSeqQuality(t *testing.T) {
+	seqs := []*linear.QSeq{
+		linear.NewQSeq(
+			"foo;size=2",
+			[]alphabet.QLetter{{L: 'A', Q: 30}, {L: 'T', Q: 10}},
+			alphabet.DNA,
+			seqio.Phred33,
+		),
+		linear.NewQSeq(
+			"bar;size=3",
+			[]alphabet.QLetter{{L: 'A', Q: 20}, {L: 'T', Q: 40}},
+			alphabet.DNA,
+			seqio.Phred33,
+		),
+	}
+
+	c := make(chan seq.Sequence)
+
+	w := new(bytes.Buffer)
+
+	wg.Add(1)
+
+	go derep.DeRepSeq(
+		c, seqio.NewWriter(w, seqio.FASTQ),
+		cluster.MinLen, cluster.MaxLen, &wg,
+	)
+
+	for _, s := range seqs {
+		c <- s
+	}
+
+	close(c)
+
+	wg.Wait()
+
+	assert.Equal(t, "@foo;size=5\nAT\n+\n?I\n", w.String(),
+		"Quality should be the highest of each position.",
+	)
+}
+
 func TestDeRepWriterError(t *testing.T) {
-	seq := linear.NewSeq(
+	s := linear.NewSeq(
 		"size=100;foo;spam=egg;bar",
 		[]alphabet.Letter("ATTC"),
 		alphabet.DNA,
@@ -120,7 +203,7 @@ func TestDeRepWriterError(t *testing.T) {
 			"DeRep should panic when its writer encounters an error.",
 		)
 
-		c <- seq
+		c <- s
 
 		close(c)
 
@@ -128,3 +211,130 @@ func TestDeRepWriterError(t *testing.T) {
 	}
 
 }
+
+func TestRunWriterError(t *testing.T) {
+	w := new(mocks.Writer)
+
+	w.On("Write", mock.Anything).Return(0, os.ErrClosed)
+
+	c := make(chan seq.Sequence, 1)
+
+	c <- linear.NewSeq("foo", []alphabet.Letter("ATTC"), alphabet.DNA)
+
+	close(c)
+
+	err := derep.Run(
+		context.Background(), c, seqio.NewWriter(w, seqio.FASTA),
+		derep.Options{},
+	)
+
+	assert.Equal(t, os.ErrClosed, err,
+		"The error of the writer should be returned.",
+	)
+}
+
+func TestRunCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := make(chan seq.Sequence, 1)
+
+	c <- linear.NewSeq("foo", []alphabet.Letter("ATTC"), alphabet.DNA)
+
+	cancel()
+
+	w := new(bytes.Buffer)
+
+	err := derep.Run(ctx, c, seqio.NewWriter(w, seqio.FASTA), derep.Options{})
+
+	assert.Equal(t, context.Canceled, err,
+		"The error of the context should be returned.",
+	)
+	assert.Empty(t, w.String(), "Nothing should be written when canceled.")
+}
+
+// writerFunc is a seqio.Writer calling a function.
+type writerFunc func(seq.Sequence) (int, error)
+
+// Write calls the function.
+func (f writerFunc) Write(s seq.Sequence) (int, error) { return f(s) }
+
+func TestRunUC(t *testing.T) {
+	b := new(bytes.Buffer)
+
+	res := runSeqs(
+		t, derep.Options{Strand: derep.Both, UC: uc.NewWriter(b)},
+		linear.NewSeq("foo;size=1", []alphabet.Letter("TTGA"), alphabet.DNA),
+		linear.NewSeq("bar;size=2", []alphabet.Letter("TCAA"), alphabet.DNA),
+		linear.NewSeq("baz;size=1", []alphabet.Letter("GG"), alphabet.DNA),
+	)
+
+	assert.Equal(t, ">foo;size=3\nTTGA\n>baz;size=1\nGG\n", res)
+	assert.Equal(t,
+		"S\t0\t4\t*\t*\t*\t*\t*\tfoo;size=3\t*\n"+
+			"H\t0\t4\t100.0\t-\t0\t0\t*\tbar;size=2\tfoo;size=3\n"+
+			"C\t0\t2\t*\t*\t*\t*\t*\tfoo;size=3\t*\n"+
+			"S\t1\t2\t*\t*\t*\t*\t*\tbaz;size=1\t*\n"+
+			"C\t1\t1\t*\t*\t*\t*\t*\tbaz;size=1\t*\n",
+		b.String(),
+		"The members of the clusters written should be written as UC.",
+	)
+}
+
+func TestRunUCRelabel(t *testing.T) {
+	r, err := relabel.New(relabel.Options{Prefix: "Uniq"})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	b := new(bytes.Buffer)
+
+	res := runSeqs(
+		t, derep.Options{Relabel: r, UC: uc.NewWriter(b)},
+		linear.NewSeq("foo;size=1", []alphabet.Letter("TTGA"), alphabet.DNA),
+		linear.NewSeq("bar;size=2", []alphabet.Letter("TTGA"), alphabet.DNA),
+	)
+
+	assert.Equal(t, ">Uniq1;size=3\nTTGA\n", res)
+	assert.Equal(t,
+		"S\t0\t4\t*\t*\t*\t*\t*\tUniq1;size=3\t*\n"+
+			"H\t0\t4\t100.0\t+\t0\t0\t*\tbar;size=2\tUniq1;size=3\n"+
+			"C\t0\t2\t*\t*\t*\t*\t*\tUniq1;size=3\t*\n",
+		b.String(),
+		"The label of the centroid should be the relabeled name in all records.",
+	)
+}
+
+func TestRunUCPrefix(t *testing.T) {
+	b := new(bytes.Buffer)
+
+	runSeqs(
+		t, derep.Options{Mode: derep.Prefix, UC: uc.NewWriter(b)},
+		linear.NewSeq("foo", []alphabet.Letter("ACGT"), alphabet.DNA),
+		linear.NewSeq("bar", []alphabet.Letter("AC"), alphabet.DNA),
+	)
+
+	assert.Equal(t,
+		"S\t0\t4\t*\t*\t*\t*\t*\tfoo;size=2\t*\n"+
+			"H\t0\t2\t100.0\t+\t0\t0\t*\tbar\tfoo;size=2\n"+
+			"C\t0\t2\t*\t*\t*\t*\t*\tfoo;size=2\t*\n",
+		b.String(),
+		"The length of a prefix should be its own.",
+	)
+}
+
+func TestRunUCDropMerged(t *testing.T) {
+	c := make(chan seq.Sequence)
+
+	for _, opt := range []derep.Options{
+		{DropMerged: true, UC: uc.NewWriter(new(bytes.Buffer))},
+		{MaxMemory: 1, UC: uc.NewWriter(new(bytes.Buffer))},
+	} {
+		err := derep.Run(
+			context.Background(), c, seqio.NewWriter(new(bytes.Buffer), seqio.FASTA),
+			opt,
+		)
+
+		assert.Error(t, err, "UC should not be written without members.")
+	}
+}
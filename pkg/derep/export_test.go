@@ -0,0 +1,54 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package derep
+
+import (
+	"runtime"
+
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+)
+
+// SetHashSum replaces the hash of the low-memory table and returns a function
+// restoring it.
+func SetHashSum(f func([]byte) [16]byte) (restore func()) {
+	old := hashSum
+	hashSum = f
+	return func() { hashSum = old }
+}
+
+// RetainedHeap adds n sequences made by gen to the table of opt and returns
+// the bytes of the heap held by the table after garbage collection.
+func RetainedHeap(opt Options, n int, gen func(int) *linear.Seq) int64 {
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	t := newTable(opt)
+
+	for i := 0; i < n; i++ {
+		t.add(cluster.ParseAnno(gen(i)))
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(t)
+
+	return int64(after.HeapAlloc) - int64(before.HeapAlloc)
+}
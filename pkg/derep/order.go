@@ -0,0 +1,96 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package derep
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+	"github.com/mys721tx/gsearch/pkg/extsort"
+)
+
+// Order is the order of the clusters written by dereplication.
+type Order int
+
+const (
+	// ByAbundance sorts the clusters as cluster.ByAbundance.
+	ByAbundance Order = iota
+	// ByInput keeps the clusters in the order of their first sequence.
+	ByInput
+	// ByLength sorts the clusters as cluster.ByLength.
+	ByLength
+	// BySequence sorts the clusters as cluster.BySequence.
+	BySequence
+)
+
+// String returns the name of the order.
+func (o Order) String() string {
+	switch o {
+	case ByAbundance:
+		return "abundance"
+	case ByInput:
+		return "input"
+	case ByLength:
+		return "length"
+	case BySequence:
+		return "sequence"
+	}
+	return fmt.Sprintf("Order(%d)", int(o))
+}
+
+// ParseOrder parses the name of an order.
+func ParseOrder(name string) (Order, error) {
+	switch name {
+	case "abundance", "size":
+		return ByAbundance, nil
+	case "input":
+		return ByInput, nil
+	case "length":
+		return ByLength, nil
+	case "sequence":
+		return BySequence, nil
+	}
+	return ByAbundance, fmt.Errorf("unknown order %q", name)
+}
+
+// sortClusters sorts clusters in the order of their first sequence by an
+// order. The ties keep the order of their first sequence.
+func sortClusters(uniq []*cluster.Cluster, o Order) {
+	switch o {
+	case ByAbundance:
+		sort.Stable(cluster.ByAbundance(uniq))
+	case ByLength:
+		sort.Stable(cluster.ByLength(uniq))
+	case BySequence:
+		sort.Stable(cluster.BySequence(uniq))
+	}
+}
+
+// less returns the extsort.Less of an order, which breaks the ties by the
+// first sequence as sortClusters.
+func (o Order) less() extsort.Less {
+	switch o {
+	case ByAbundance:
+		return extsort.ByAbundance
+	case ByLength:
+		return extsort.ByLength
+	case BySequence:
+		return extsort.BySequence
+	}
+	return extsort.ByOrder
+}
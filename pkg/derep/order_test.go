@@ -0,0 +1,101 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package derep_test
+
+import (
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/derep"
+	"github.com/mys721tx/gsearch/pkg/relabel"
+)
+
+func TestParseOrder(t *testing.T) {
+	for name, exp := range map[string]derep.Order{
+		"abundance": derep.ByAbundance,
+		"input":     derep.ByInput,
+		"length":    derep.ByLength,
+		"sequence":  derep.BySequence,
+	} {
+		o, err := derep.ParseOrder(name)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, exp, o, "Order should be parsed from its name.")
+			assert.Equal(t, name, o.String(), "Name should be the same.")
+		}
+	}
+
+	_, err := derep.ParseOrder("random")
+
+	assert.Error(t, err, "Unknown order should return an error.")
+}
+
+func orderSeqs() []*linear.Seq {
+	return []*linear.Seq{
+		linear.NewSeq("d;size=1", []alphabet.Letter("TT"), alphabet.DNA),
+		linear.NewSeq("c;size=3", []alphabet.Letter("GGG"), alphabet.DNA),
+		linear.NewSeq("b;size=1", []alphabet.Letter("AAAA"), alphabet.DNA),
+		linear.NewSeq("a;size=1", []alphabet.Letter("CC"), alphabet.DNA),
+		linear.NewSeq("e;size=1", []alphabet.Letter("TT"), alphabet.DNA),
+		linear.NewSeq("a;size=1", []alphabet.Letter("AC"), alphabet.DNA),
+	}
+}
+
+func TestRunOrder(t *testing.T) {
+	for o, exp := range map[derep.Order]string{
+		derep.ByAbundance: ">c;size=3\nGGG\n>d;size=2\nTT\n>a;size=1\nCC\n" +
+			">a;size=1\nAC\n>b;size=1\nAAAA\n",
+		derep.ByInput: ">d;size=2\nTT\n>c;size=3\nGGG\n>b;size=1\nAAAA\n" +
+			">a;size=1\nCC\n>a;size=1\nAC\n",
+		derep.ByLength: ">b;size=1\nAAAA\n>c;size=3\nGGG\n>d;size=2\nTT\n" +
+			">a;size=1\nCC\n>a;size=1\nAC\n",
+		derep.BySequence: ">b;size=1\nAAAA\n>a;size=1\nAC\n>a;size=1\nCC\n" +
+			">c;size=3\nGGG\n>d;size=2\nTT\n",
+	} {
+		for _, opt := range []derep.Options{
+			{Order: o},
+			{Order: o, Threads: 3},
+			{Order: o, MaxMemory: 1},
+		} {
+			assert.Equal(t, exp, runSeqs(t, opt, orderSeqs()...),
+				"The clusters should be sorted %v, then by input.", o,
+			)
+		}
+	}
+}
+
+func TestRunRelabel(t *testing.T) {
+	r, err := relabel.New(relabel.Options{Prefix: "Uniq", Keep: true})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	res := runSeqs(
+		t, derep.Options{Relabel: r, Min: 2},
+		orderSeqs()...,
+	)
+
+	assert.Equal(t,
+		">Uniq1;label=c;size=3\nGGG\n>Uniq2;label=d;size=2\nTT\n", res,
+		"The clusters written should be relabeled in their order.",
+	)
+}
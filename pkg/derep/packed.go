@@ -0,0 +1,194 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package derep
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/fnv"
+
+	"github.com/biogo/biogo/alphabet"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+)
+
+// code is the 2-bit code of the nucleotides. Other letters are 0xff.
+var code = func() (t [256]byte) {
+	for i := range t {
+		t[i] = 0xff
+	}
+
+	for i, l := range []byte("ACGT") {
+		t[l] = byte(i)
+	}
+
+	return t
+}()
+
+// pack encodes the letters in 2 bits each, four letters per byte. ok is false
+// if any letter is not one of A, C, G and T.
+func pack(l []byte) (p []byte, ok bool) {
+	p = make([]byte, (len(l)+3)/4)
+
+	for i, v := range l {
+		c := code[v]
+
+		if c == 0xff {
+			return nil, false
+		}
+
+		p[i/4] |= c << (uint(i%4) * 2)
+	}
+
+	return p, true
+}
+
+// unpack decodes n letters packed by pack.
+func unpack(p []byte, n int) alphabet.Letters {
+	l := make(alphabet.Letters, n)
+
+	for i := range l {
+		l[i] = alphabet.Letter("ACGT"[p[i/4]>>(uint(i%4)*2)&3])
+	}
+
+	return l
+}
+
+// hashSum returns the 128-bit FNV-1a hash of a key.
+var hashSum = func(b []byte) (h [16]byte) {
+	f := fnv.New128a()
+	f.Write(b)
+	f.Sum(h[:0])
+	return h
+}
+
+// hashKey returns the hash of a key of n letters. The hash covers the length
+// and the encoding, as packed keys of different lengths may have the same
+// bytes.
+func hashKey(key []byte, n int, twoBit bool) [16]byte {
+	b := make([]byte, 9, 9+len(key))
+	binary.LittleEndian.PutUint64(b, uint64(n))
+
+	if twoBit {
+		b[8] = 1
+	}
+
+	return hashSum(append(b, key...))
+}
+
+// hashEntry is a unique sequence in a hashTable.
+type hashEntry struct {
+	// c is the cluster without its letters.
+	c *cluster.Cluster
+	// key is the letters of the key, packed if twoBit.
+	key    []byte
+	n      int
+	twoBit bool
+	// rev is true if the cluster is the reverse complement of the key.
+	rev bool
+	// next is the next entry of the same hash.
+	next *hashEntry
+}
+
+// equal reports whether the entry is of a key.
+func (e *hashEntry) equal(key []byte, n int, twoBit bool) bool {
+	return e.n == n && e.twoBit == twoBit && bytes.Equal(e.key, key)
+}
+
+// hashTable is a table keyed by the 128-bit hash of the packed sequences.
+//
+// The letters of a cluster are released when it is held, and the key is
+// stored in 2 bits per letter if it only has A, C, G and T. When two keys have
+// the same hash, their letters are compared, so a collision never merges
+// different sequences.
+type hashTable struct {
+	rep map[[16]byte]*hashEntry
+	// uniq holds the entries in the order of their first sequence.
+	uniq []*hashEntry
+	opt  Options
+}
+
+// newHashTable returns an empty hashTable.
+func newHashTable(opt Options) *hashTable {
+	return &hashTable{
+		rep: make(map[[16]byte]*hashEntry),
+		opt: opt,
+	}
+}
+
+func (t *hashTable) add(c *cluster.Cluster) bool {
+	k := []byte(Key(&c.Seq, t.opt.Strand))
+	rev := !bytes.Equal(k, alphabet.LettersToBytes(c.Seq.Seq))
+
+	key, twoBit := pack(k)
+
+	if !twoBit {
+		key = k
+	}
+
+	h := hashKey(key, len(k), twoBit)
+
+	for e := t.rep[h]; e != nil; e = e.next {
+		if e.equal(key, len(k), twoBit) {
+			if rev != e.rev {
+				reverse(c)
+			}
+			e.c.Merge(c, t.opt.Policies)
+			return false
+		}
+	}
+
+	c.Seq.Seq = nil
+
+	e := &hashEntry{
+		c:      c,
+		key:    key,
+		n:      len(k),
+		twoBit: twoBit,
+		rev:    rev,
+		next:   t.rep[h],
+	}
+
+	t.rep[h] = e
+	t.uniq = append(t.uniq, e)
+
+	return true
+}
+
+// clusters restores the letters of the clusters and returns them.
+func (t *hashTable) clusters() []*cluster.Cluster {
+	uniq := make([]*cluster.Cluster, len(t.uniq))
+
+	for i, e := range t.uniq {
+		var l alphabet.Letters
+
+		if e.twoBit {
+			l = unpack(e.key, e.n)
+		} else {
+			l = alphabet.BytesToLetters(e.key)
+		}
+
+		if e.rev {
+			l, _ = revComp(l, e.c.Alpha)
+		}
+
+		e.c.Seq.Seq = l
+		uniq[i] = e.c
+	}
+
+	return uniq
+}
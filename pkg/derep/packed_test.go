@@ -0,0 +1,186 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package derep_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+	"github.com/mys721tx/gsearch/pkg/derep"
+)
+
+func lowMemorySeqs() []*linear.Seq {
+	return []*linear.Seq{
+		linear.NewSeq("a;size=2", []alphabet.Letter("ACGT"), alphabet.DNA),
+		linear.NewSeq("b;size=3", []alphabet.Letter("ACGTA"), alphabet.DNA),
+		linear.NewSeq("c;size=1", []alphabet.Letter("ACGTAA"), alphabet.DNA),
+		linear.NewSeq("d;size=5", []alphabet.Letter("ACGNT"), alphabet.DNA),
+		linear.NewSeq("e;size=1", []alphabet.Letter("acgt"), alphabet.DNA),
+		linear.NewSeq("f;size=1", []alphabet.Letter("ACGTA"), alphabet.DNA),
+		linear.NewSeq("g;size=4", []alphabet.Letter("TACGT"), alphabet.DNA),
+		linear.NewSeq("h;size=1", []alphabet.Letter("ACGNT"), alphabet.DNA),
+		linear.NewSeq("i;size=1", []alphabet.Letter(""), alphabet.DNA),
+		linear.NewSeq("j;size=2", []alphabet.Letter("ACGT"), alphabet.DNA),
+	}
+}
+
+func TestRunLowMemory(t *testing.T) {
+	for _, opt := range []derep.Options{
+		{},
+		{Strand: derep.Both},
+		{Mode: derep.Prefix},
+	} {
+		want := runSeqs(t, opt, lowMemorySeqs()...)
+
+		opt.LowMemory = true
+
+		assert.Equal(t, want, runSeqs(t, opt, lowMemorySeqs()...),
+			"The output of LowMemory should be the same as the default.",
+		)
+	}
+}
+
+func TestLowMemoryRetainedHeap(t *testing.T) {
+	const n, size = 256, 8192
+
+	r := rand.New(rand.NewSource(1))
+
+	gen := func(i int) *linear.Seq {
+		l := make([]alphabet.Letter, size)
+
+		for j := range l {
+			l[j] = alphabet.Letter("ACGT"[r.Intn(4)])
+		}
+
+		return linear.NewSeq(fmt.Sprintf("s%d", i), l, alphabet.DNA)
+	}
+
+	held := derep.RetainedHeap(derep.Options{LowMemory: true}, n, gen)
+
+	assert.Less(t, held, int64(n*size/2),
+		"LowMemory should not hold the letters of the input sequences.",
+	)
+}
+
+func TestRunLowMemoryCollision(t *testing.T) {
+	defer derep.SetHashSum(func([]byte) (h [16]byte) { return h })()
+
+	for _, opt := range []derep.Options{{}, {Strand: derep.Both}} {
+		opt.LowMemory = true
+
+		want := runSeqs(t, derep.Options{Strand: opt.Strand}, lowMemorySeqs()...)
+
+		assert.Equal(t, want, runSeqs(t, opt, lowMemorySeqs()...),
+			"Sequences of the same hash should not be merged.",
+		)
+	}
+}
+
+func TestRunLowMemoryQuality(t *testing.T) {
+	newSeqs := func() []*linear.QSeq {
+		return []*linear.QSeq{
+			linear.NewQSeq(
+				"foo;size=2",
+				[]alphabet.QLetter{{L: 'A', Q: 30}, {L: 'C', Q: 10}},
+				alphabet.DNA,
+				alphabet.Sanger,
+			),
+			linear.NewQSeq(
+				"bar;size=3",
+				[]alphabet.QLetter{{L: 'G', Q: 20}, {L: 'T', Q: 40}},
+				alphabet.DNA,
+				alphabet.Sanger,
+			),
+		}
+	}
+
+	run := func(opt derep.Options) []*cluster.Cluster {
+		c := make(chan seq.Sequence, 2)
+
+		for _, s := range newSeqs() {
+			c <- s
+		}
+
+		close(c)
+
+		var res []*cluster.Cluster
+
+		err := derep.Run(
+			context.Background(), c,
+			writerFunc(func(s seq.Sequence) (int, error) {
+				res = append(res, s.(*cluster.Cluster))
+				return 0, nil
+			}),
+			opt,
+		)
+
+		assert.NoError(t, err)
+
+		return res
+	}
+
+	want := run(derep.Options{Strand: derep.Both})
+	res := run(derep.Options{Strand: derep.Both, LowMemory: true})
+
+	if assert.Len(t, res, 1) && assert.Len(t, want, 1) {
+		assert.Equal(t, want[0].String(), res[0].String(),
+			"The letters should be restored in their orientation.",
+		)
+		assert.Equal(t, want[0].Qual, res[0].Qual,
+			"The quality should be merged in the same orientation.",
+		)
+		assert.Equal(t, want[0].Merged, res[0].Merged,
+			"The annotations should be marked by their orientation.",
+		)
+	}
+}
+
+func TestRunDropMerged(t *testing.T) {
+	for _, low := range []bool{false, true} {
+		c := make(chan seq.Sequence, 2)
+
+		c <- linear.NewSeq("foo", []alphabet.Letter("ATTC"), alphabet.DNA)
+		c <- linear.NewSeq("bar", []alphabet.Letter("ATTC"), alphabet.DNA)
+
+		close(c)
+
+		var res []*cluster.Cluster
+
+		err := derep.Run(
+			context.Background(), c,
+			writerFunc(func(s seq.Sequence) (int, error) {
+				res = append(res, s.(*cluster.Cluster))
+				return 0, nil
+			}),
+			derep.Options{LowMemory: low, DropMerged: true},
+		)
+
+		if assert.NoError(t, err) && assert.Len(t, res, 1) {
+			assert.Nil(t, res[0].Merged, "Merged should be dropped.")
+			assert.Equal(t, 2, res[0].Size, "Size should still be summed.")
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package derep
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/biogo/biogo/alphabet"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+)
+
+// Mode is the way sequences are matched by dereplication.
+type Mode int
+
+const (
+	// FullLength merges sequences that are identical, as the
+	// --derep_fulllength option of vsearch.
+	FullLength Mode = iota
+	// Prefix also merges a sequence into a longer sequence that starts with
+	// it, as the --derep_prefix option of vsearch.
+	Prefix
+)
+
+// String returns the name of the mode.
+func (m Mode) String() string {
+	switch m {
+	case FullLength:
+		return "full"
+	case Prefix:
+		return "prefix"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// ParseMode parses the name of a mode.
+func ParseMode(name string) (Mode, error) {
+	switch name {
+	case "full", "fulllength":
+		return FullLength, nil
+	case "prefix":
+		return Prefix, nil
+	}
+	return FullLength, fmt.Errorf("unknown mode %q", name)
+}
+
+var errPrefixStrand = errors.New("derep: prefix mode only supports the plus strand")
+
+// prefixNode is a unique sequence in the sorted-prefix index.
+type prefixNode struct {
+	c *cluster.Cluster
+	// order is the index of the cluster in the input.
+	order int
+	// best is the best cluster among the sequences starting with c.
+	best *prefixNode
+}
+
+// better reports whether a is a better target than b: the longer, then the
+// more abundant, then the earlier one.
+func better(a, b *prefixNode) bool {
+	switch {
+	case b == nil:
+		return true
+	case a.c.Len() != b.c.Len():
+		return a.c.Len() > b.c.Len()
+	case a.c.Size != b.c.Size:
+		return a.c.Size > b.c.Size
+	}
+	return a.order < b.order
+}
+
+// letters returns the letters of a cluster as bytes without copying.
+func letters(c *cluster.Cluster) []byte {
+	return alphabet.LettersToBytes(c.Seq.Seq)
+}
+
+// mergePrefix merges each cluster whose sequence is a prefix of longer
+// sequences into the longest of them. Ties are broken by the abundance and
+// then the order of the clusters. It returns the remaining clusters in their
+// order.
+//
+// The clusters are sorted by their sequences, so that the sequences starting
+// with a prefix follow the prefix. A stack holds the prefixes of the current
+// sequence, and each prefix collects its best target as the stack is popped.
+func mergePrefix(uniq []*cluster.Cluster, p cluster.Policies) []*cluster.Cluster {
+	nodes := make([]*prefixNode, len(uniq))
+
+	for i, c := range uniq {
+		nodes[i] = &prefixNode{c: c, order: i}
+	}
+
+	sorted := append([]*prefixNode(nil), nodes...)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(letters(sorted[i].c), letters(sorted[j].c)) < 0
+	})
+
+	var stack []*prefixNode
+
+	pop := func() {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		cand := n.best
+
+		if better(n, cand) {
+			cand = n
+		}
+
+		if len(stack) > 0 {
+			if top := stack[len(stack)-1]; better(cand, top.best) {
+				top.best = cand
+			}
+		}
+	}
+
+	for _, n := range sorted {
+		for len(stack) > 0 &&
+			!bytes.HasPrefix(letters(n.c), letters(stack[len(stack)-1].c)) {
+			pop()
+		}
+		stack = append(stack, n)
+	}
+
+	for len(stack) > 0 {
+		pop()
+	}
+
+	var res []*cluster.Cluster
+
+	for _, n := range nodes {
+		if n.best == nil {
+			res = append(res, n.c)
+		}
+	}
+
+	for _, n := range nodes {
+		if n.best != nil {
+			n.best.c.Merge(n.c, p)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,115 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package derep_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/derep"
+	"github.com/mys721tx/gsearch/pkg/seqio"
+)
+
+func TestParseMode(t *testing.T) {
+	for name, exp := range map[string]derep.Mode{
+		"full":   derep.FullLength,
+		"prefix": derep.Prefix,
+	} {
+		m, err := derep.ParseMode(name)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, exp, m, "Mode should be parsed from its name.")
+			assert.Equal(t, name, m.String(), "Name should be the same.")
+		}
+	}
+
+	_, err := derep.ParseMode("suffix")
+
+	assert.Error(t, err, "Unknown mode should return an error.")
+}
+
+// runSeqs dereplicates sequences by Run and returns the output as FASTA.
+func runSeqs(t *testing.T, opt derep.Options, seqs ...*linear.Seq) string {
+	c := make(chan seq.Sequence, len(seqs))
+
+	for _, s := range seqs {
+		c <- s
+	}
+
+	close(c)
+
+	w := new(bytes.Buffer)
+
+	err := derep.Run(
+		context.Background(), c, seqio.NewWriter(w, seqio.FASTA), opt,
+	)
+
+	assert.NoError(t, err)
+
+	return w.String()
+}
+
+func TestRunPrefix(t *testing.T) {
+	res := runSeqs(
+		t, derep.Options{Mode: derep.Prefix},
+		linear.NewSeq("a;size=2", []alphabet.Letter("ACGT"), alphabet.DNA),
+		linear.NewSeq("b;size=3", []alphabet.Letter("ACG"), alphabet.DNA),
+		linear.NewSeq("c;size=1", []alphabet.Letter("AC"), alphabet.DNA),
+		linear.NewSeq("d;size=5", []alphabet.Letter("ACTT"), alphabet.DNA),
+		linear.NewSeq("e;size=1", []alphabet.Letter("GG"), alphabet.DNA),
+		linear.NewSeq("f;size=1", []alphabet.Letter("ACG"), alphabet.DNA),
+	)
+
+	assert.Equal(
+		t,
+		">a;size=6\nACGT\n>d;size=6\nACTT\n>e;size=1\nGG\n",
+		res,
+		"Prefixes should be merged into the longest, then most abundant, sequence.",
+	)
+}
+
+func TestRunPrefixTie(t *testing.T) {
+	res := runSeqs(
+		t, derep.Options{Mode: derep.Prefix},
+		linear.NewSeq("a", []alphabet.Letter("A"), alphabet.DNA),
+		linear.NewSeq("b", []alphabet.Letter("AT"), alphabet.DNA),
+		linear.NewSeq("c", []alphabet.Letter("AG"), alphabet.DNA),
+	)
+
+	assert.Equal(t, ">b;size=2\nAT\n>c;size=1\nAG\n", res,
+		"Ties should be merged into the first sequence.",
+	)
+}
+
+func TestRunPrefixStrand(t *testing.T) {
+	c := make(chan seq.Sequence)
+
+	err := derep.Run(
+		context.Background(), c,
+		seqio.NewWriter(new(bytes.Buffer), seqio.FASTA),
+		derep.Options{Mode: derep.Prefix, Strand: derep.Both},
+	)
+
+	assert.Error(t, err, "Prefix mode should not support strand both.")
+}
@@ -0,0 +1,165 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package derep
+
+import (
+	"context"
+	"sort"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+)
+
+// ShardBuffer is the size of the channel of each shard.
+const ShardBuffer = 64
+
+// shardItem is a sequence sent to a shard with its index in the input, or the
+// cluster of the sequence once it is parsed.
+type shardItem struct {
+	i int64
+	s seq.Sequence
+	c *cluster.Cluster
+}
+
+// shard is a table of the clusters whose keys hash to the shard.
+type shard struct {
+	t table
+	// orders holds the index of the first sequence of each cluster in t.
+	orders []int64
+}
+
+// FNV-1a parameters of the hash of shardOf.
+const (
+	fnvOffset uint64 = 14695981039346656037
+	fnvPrime  uint64 = 1099511628211
+)
+
+// shardOf returns the shard of a sequence among n shards.
+//
+// The letters are hashed in place, without building the key of the sequence.
+// With Both, the smaller of the hashes of the sequence and its reverse
+// complement is used, so the sequences of the same Key go to the same shard.
+func shardOf(s seq.Sequence, strand Strand, n int) int {
+	h := fnvOffset
+
+	for i := 0; i < s.Len(); i++ {
+		h = (h ^ uint64(s.At(i).L)) * fnvPrime
+	}
+
+	if comp, ok := s.Alphabet().(alphabet.Complementor); ok && strand == Both {
+		t := comp.ComplementTable()
+		r := fnvOffset
+
+		for i := s.Len() - 1; i >= 0; i-- {
+			r = (r ^ uint64(t[s.At(i).L])) * fnvPrime
+		}
+
+		if r < h {
+			h = r
+		}
+	}
+
+	return int(h % uint64(n))
+}
+
+// runShards dereplicates the sequences from a channel in opt.Threads shards
+// and returns the clusters in the order of their first sequence.
+//
+// The sequences are sent to the shard of the hash of their letters, so all the
+// sequences of a cluster are merged by the same shard in the order of the
+// input. Each shard parses and keys its own sequences. The clusters of the
+// shards are then sorted by their first sequence, which gives the same
+// clusters in the same order as a single table.
+func runShards(ctx context.Context, in <-chan seq.Sequence, opt Options) ([]*cluster.Cluster, error) {
+	g, gctx := errgroup.WithContext(ctx)
+
+	shards := make([]shard, opt.Threads)
+	chans := make([]chan shardItem, opt.Threads)
+
+	for i := range shards {
+		sh, ch := &shards[i], make(chan shardItem, ShardBuffer)
+
+		sh.t, chans[i] = newTable(opt), ch
+
+		g.Go(func() error {
+			for it := range ch {
+				c := cluster.ParseAnno(it.s)
+
+				if opt.DropMerged {
+					c.Merged = nil
+				}
+
+				if sh.t.add(c) {
+					sh.orders = append(sh.orders, it.i)
+				}
+			}
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		defer func() {
+			for _, ch := range chans {
+				close(ch)
+			}
+		}()
+
+		for i := int64(0); ; i++ {
+			select {
+			case s, ok := <-in:
+				if !ok {
+					return nil
+				}
+
+				ch := chans[shardOf(s, opt.Strand, len(chans))]
+
+				select {
+				case ch <- shardItem{i: i, s: s}:
+				case <-gctx.Done():
+					return gctx.Err()
+				}
+			case <-gctx.Done():
+				return gctx.Err()
+			}
+		}
+	})
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	var items []shardItem
+
+	for _, sh := range shards {
+		for i, c := range sh.t.clusters() {
+			items = append(items, shardItem{i: sh.orders[i], c: c})
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].i < items[j].i })
+
+	uniq := make([]*cluster.Cluster, len(items))
+
+	for i, it := range items {
+		uniq[i] = it.c
+	}
+
+	return uniq, nil
+}
@@ -0,0 +1,76 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package derep_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+	"github.com/mys721tx/gsearch/pkg/derep"
+	"github.com/mys721tx/gsearch/pkg/seqio"
+)
+
+func TestRunThreads(t *testing.T) {
+	policies := cluster.Policies{"sample": cluster.Concat}
+
+	for _, opt := range []derep.Options{
+		{},
+		{Policies: policies, Strand: derep.Both},
+		{Policies: policies, LowMemory: true},
+		{Mode: derep.Prefix},
+		{Min: 2},
+	} {
+		want := runSeqs(t, opt, spillSeqs()...)
+
+		for _, n := range []int{2, 3, 8} {
+			opt.Threads = n
+
+			assert.Equal(t, want, runSeqs(t, opt, spillSeqs()...),
+				"The output of %d threads should be the same as one.", n,
+			)
+		}
+	}
+}
+
+func TestRunThreadsCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := make(chan seq.Sequence, 1)
+
+	c <- linear.NewSeq("foo", []alphabet.Letter("ATTC"), alphabet.DNA)
+
+	cancel()
+
+	w := new(bytes.Buffer)
+
+	err := derep.Run(
+		ctx, c, seqio.NewWriter(w, seqio.FASTA), derep.Options{Threads: 4},
+	)
+
+	assert.Equal(t, context.Canceled, err,
+		"The error of the context should be returned.",
+	)
+	assert.Empty(t, w.String(), "Nothing should be written when canceled.")
+}
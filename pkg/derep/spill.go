@@ -0,0 +1,195 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package derep
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/biogo/biogo/seq"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+	"github.com/mys721tx/gsearch/pkg/extsort"
+	"github.com/mys721tx/gsearch/pkg/seqio"
+)
+
+var errPrefixSpill = errors.New("derep: prefix mode does not support MaxMemory")
+
+// runSpill dereplicates the sequences from a channel as Run within
+// opt.MaxMemory.
+//
+// The unique sequences are held in a table until their estimated memory
+// reaches opt.MaxMemory, when they are sorted by their keys and spilled to a
+// run in opt.TempDir. The runs are then merged by their keys, summing the
+// clusters of the same key in the order of the input, and sorted by
+// opt.Order. The output is the same as holding all of
+// the sequences in memory, except that Cluster.Merged is always discarded.
+//
+// If no run is spilled, the clusters are written from memory.
+func runSpill(ctx context.Context, in <-chan seq.Sequence, w seqio.Writer, opt Options) error {
+	if opt.Mode == Prefix {
+		return errPrefixSpill
+	}
+
+	keys := extsort.NewSorter(extsort.ByKey, 0, opt.TempDir)
+
+	defer keys.Close()
+
+	t := newTable(opt)
+
+	// orders holds the index of the first sequence of each cluster in t.
+	var orders []int64
+	var size int64
+
+	// hold moves the clusters in t to keys.
+	hold := func() error {
+		for i, c := range t.clusters() {
+			err := keys.Add(&extsort.Record{
+				Key:     Key(&c.Seq, opt.Strand),
+				Order:   orders[i],
+				Cluster: c,
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		t, orders, size = newTable(opt), nil, 0
+
+		return nil
+	}
+
+	err := receive(ctx, in, func(i int64, c *cluster.Cluster) error {
+		c.Merged = nil
+
+		if !t.add(c) {
+			return nil
+		}
+
+		orders = append(orders, i)
+		// The table also holds a key of the sequence.
+		size += extsort.Footprint(&extsort.Record{Cluster: c}) + int64(c.Len())
+
+		if size < opt.MaxMemory {
+			return nil
+		}
+
+		if err := hold(); err != nil {
+			return err
+		}
+
+		return keys.Spill()
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if keys.Runs() == 0 {
+		uniq := t.clusters()
+
+		sortClusters(uniq, opt.Order)
+
+		for _, c := range uniq {
+			if err := write(w, c, opt); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := hold(); err != nil {
+		return err
+	}
+
+	uniq := extsort.NewSorter(opt.Order.less(), opt.MaxMemory, opt.TempDir)
+
+	defer uniq.Close()
+
+	if err := mergeRuns(ctx, keys, uniq, opt); err != nil {
+		return err
+	}
+
+	it, err := uniq.Sort()
+
+	if err != nil {
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r, err := it.Next()
+
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := write(w, r.Cluster, opt); err != nil {
+			return err
+		}
+	}
+}
+
+// mergeRuns merges the records of the same key from keys, and adds the merged
+// records passing the abundance filter to uniq.
+func mergeRuns(ctx context.Context, keys, uniq *extsort.Sorter, opt Options) error {
+	it, err := keys.Sort()
+
+	if err != nil {
+		return err
+	}
+
+	var r *extsort.Record
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		next, err := it.Next()
+
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if err == nil && r != nil && next.Key == r.Key {
+			orient(r.Cluster, next.Cluster)
+			r.Merge(next.Cluster, opt.Policies)
+			continue
+		}
+
+		if r != nil && r.PassFilter(opt.Min, opt.Max) {
+			if err := uniq.Add(r); err != nil {
+				return err
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+
+		r = next
+	}
+}
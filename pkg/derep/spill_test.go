@@ -0,0 +1,155 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package derep_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+	"github.com/mys721tx/gsearch/pkg/derep"
+	"github.com/mys721tx/gsearch/pkg/seqio"
+)
+
+func spillSeqs() []*linear.Seq {
+	return append(
+		lowMemorySeqs(),
+		linear.NewSeq(
+			"k;sample=A;ee=0.5;size=2", []alphabet.Letter("ACGGT"), alphabet.DNA,
+		),
+		linear.NewSeq(
+			"l;sample=B;run=1;size=3", []alphabet.Letter("ACCGT"), alphabet.DNA,
+		),
+		linear.NewSeq(
+			"m;sample=C;ee=0.25;size=1", []alphabet.Letter("ACGGT"), alphabet.DNA,
+		),
+		linear.NewSeq(
+			"n;sample=A;size=7", []alphabet.Letter("ACGGT"), alphabet.DNA,
+		),
+	)
+}
+
+func TestRunSpill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "derep")
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer os.RemoveAll(dir)
+
+	policies := cluster.Policies{
+		"sample":           cluster.Concat,
+		"ee":               cluster.Sum,
+		cluster.DefaultKey: cluster.Drop,
+	}
+
+	for _, opt := range []derep.Options{
+		{},
+		{Policies: policies},
+		{Policies: policies, Strand: derep.Both},
+		{Policies: policies, Strand: derep.Both, LowMemory: true},
+		{Min: 2, Max: 5},
+	} {
+		want := runSeqs(t, opt, spillSeqs()...)
+
+		opt.TempDir = dir
+
+		for _, max := range []int64{1, 600, 1 << 30} {
+			opt.MaxMemory = max
+
+			assert.Equal(t, want, runSeqs(t, opt, spillSeqs()...),
+				"The output should be the same as in memory.",
+			)
+		}
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+
+	assert.Empty(t, files, "The spilled runs should be removed.")
+}
+
+func TestRunSpillQuality(t *testing.T) {
+	newSeqs := func() []seq.Sequence {
+		return []seq.Sequence{
+			linear.NewQSeq(
+				"foo;size=2",
+				[]alphabet.QLetter{{L: 'A', Q: 30}, {L: 'C', Q: 10}},
+				alphabet.DNA,
+				seqio.Phred33,
+			),
+			linear.NewQSeq(
+				"bar;size=1",
+				[]alphabet.QLetter{{L: 'T', Q: 5}},
+				alphabet.DNA,
+				seqio.Phred33,
+			),
+			linear.NewQSeq(
+				"baz;size=3",
+				[]alphabet.QLetter{{L: 'G', Q: 20}, {L: 'T', Q: 40}},
+				alphabet.DNA,
+				seqio.Phred33,
+			),
+		}
+	}
+
+	run := func(opt derep.Options) string {
+		c := make(chan seq.Sequence, 3)
+
+		for _, s := range newSeqs() {
+			c <- s
+		}
+
+		close(c)
+
+		w := new(bytes.Buffer)
+
+		err := derep.Run(
+			context.Background(), c, seqio.NewWriter(w, seqio.FASTQ), opt,
+		)
+
+		assert.NoError(t, err)
+
+		return w.String()
+	}
+
+	assert.Equal(t,
+		run(derep.Options{Strand: derep.Both}),
+		run(derep.Options{Strand: derep.Both, MaxMemory: 1}),
+		"The quality should be merged as in memory.",
+	)
+}
+
+func TestRunSpillPrefix(t *testing.T) {
+	c := make(chan seq.Sequence)
+
+	err := derep.Run(
+		context.Background(), c, seqio.NewWriter(new(bytes.Buffer), seqio.FASTA),
+		derep.Options{Mode: derep.Prefix, MaxMemory: 1},
+	)
+
+	assert.Error(t, err, "Prefix mode should not be spilled.")
+}
@@ -0,0 +1,142 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package derep
+
+import (
+	"fmt"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+)
+
+// Strand is the strand compared by dereplication.
+type Strand int
+
+const (
+	// Plus compares sequences as they are.
+	Plus Strand = iota
+	// Both also compares sequences with their reverse complements, as the
+	// --strand both option of vsearch.
+	Both
+)
+
+// String returns the name of the strand.
+func (s Strand) String() string {
+	switch s {
+	case Plus:
+		return "plus"
+	case Both:
+		return "both"
+	}
+	return fmt.Sprintf("Strand(%d)", int(s))
+}
+
+// ParseStrand parses the name of a strand.
+func ParseStrand(name string) (Strand, error) {
+	switch name {
+	case "plus":
+		return Plus, nil
+	case "both":
+		return Both, nil
+	}
+	return Plus, fmt.Errorf("unknown strand %q", name)
+}
+
+// Key returns the key of a sequence in dereplication.
+//
+// With Plus, the key is the sequence. With Both, the key is the
+// lexicographically smaller of the sequence and its reverse complement, so a
+// sequence and its reverse complement share a key. Sequences whose alphabet
+// cannot be complemented are keyed as Plus.
+func Key(s *linear.Seq, strand Strand) string {
+	k := s.String()
+
+	if strand != Both {
+		return k
+	}
+
+	if rc, ok := revComp(s.Seq, s.Alpha); ok {
+		if r := rc.String(); r < k {
+			return r
+		}
+	}
+
+	return k
+}
+
+// revComp returns the reverse complement of the letters. ok is false if the
+// alphabet cannot be complemented.
+func revComp(l alphabet.Letters, a alphabet.Alphabet) (rc alphabet.Letters, ok bool) {
+	comp, ok := a.(alphabet.Complementor)
+
+	if !ok {
+		return nil, false
+	}
+
+	t := comp.ComplementTable()
+	rc = make(alphabet.Letters, len(l))
+
+	for i, v := range l {
+		rc[len(rc)-1-i] = t[v]
+	}
+
+	return rc, true
+}
+
+// sameLetters reports whether two sequences have the same letters.
+func sameLetters(a, b alphabet.Letters) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// orient reverse complements c to the orientation of r if they differ, as
+// reverse.
+func orient(r, c *cluster.Cluster) {
+	if !sameLetters(r.Seq.Seq, c.Seq.Seq) {
+		reverse(c)
+	}
+}
+
+// reverse reverse complements c and marks the merged annotations of c as
+// seq.Minus.
+func reverse(c *cluster.Cluster) {
+	// The letters may be shared with the input sequence.
+	c.Seq.Seq = append(alphabet.Letters(nil), c.Seq.Seq...)
+	c.RevComp()
+
+	for i, m := range c.Merged {
+		a := m.CloneAnnotation()
+		a.Strand = seq.Minus
+		c.Merged[i] = &cluster.Member{
+			Annotation: a,
+			Len:        m.Len,
+			Identity:   m.Identity,
+		}
+	}
+}
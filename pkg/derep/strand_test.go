@@ -0,0 +1,161 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package derep_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+	"github.com/mys721tx/gsearch/pkg/derep"
+	"github.com/mys721tx/gsearch/pkg/seqio"
+)
+
+func TestParseStrand(t *testing.T) {
+	for name, exp := range map[string]derep.Strand{
+		"plus": derep.Plus,
+		"both": derep.Both,
+	} {
+		s, err := derep.ParseStrand(name)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, exp, s, "Strand should be parsed from its name.")
+			assert.Equal(t, name, s.String(), "Name should be the same.")
+		}
+	}
+
+	_, err := derep.ParseStrand("minus")
+
+	assert.Error(t, err, "Unknown strand should return an error.")
+}
+
+func TestKey(t *testing.T) {
+	fwd := linear.NewSeq("foo", []alphabet.Letter("TTGA"), alphabet.DNA)
+	rev := linear.NewSeq("bar", []alphabet.Letter("TCAA"), alphabet.DNA)
+
+	assert.Equal(t, "TTGA", derep.Key(fwd, derep.Plus),
+		"Key should be the sequence on the plus strand.",
+	)
+	assert.Equal(t, "TCAA", derep.Key(fwd, derep.Both),
+		"Key should be the smaller of the sequence and its reverse complement.",
+	)
+	assert.Equal(t, "TCAA", derep.Key(rev, derep.Both),
+		"Key should be the smaller of the sequence and its reverse complement.",
+	)
+
+	prot := linear.NewSeq("baz", []alphabet.Letter("TTGA"), alphabet.Protein)
+
+	assert.Equal(t, "TTGA", derep.Key(prot, derep.Both),
+		"Key should be the sequence when it cannot be complemented.",
+	)
+}
+
+func TestRunStrandBoth(t *testing.T) {
+	seqs := []*linear.QSeq{
+		linear.NewQSeq(
+			"foo;size=2",
+			[]alphabet.QLetter{
+				{L: 'T', Q: 10}, {L: 'T', Q: 20},
+				{L: 'G', Q: 30}, {L: 'A', Q: 10},
+			},
+			alphabet.DNA,
+			seqio.Phred33,
+		),
+		linear.NewQSeq(
+			"bar;size=3",
+			[]alphabet.QLetter{
+				{L: 'T', Q: 40}, {L: 'C', Q: 10},
+				{L: 'A', Q: 10}, {L: 'A', Q: 10},
+			},
+			alphabet.DNA,
+			seqio.Phred33,
+		),
+	}
+
+	c := make(chan seq.Sequence, len(seqs))
+
+	for _, s := range seqs {
+		c <- s
+	}
+
+	close(c)
+
+	w := new(bytes.Buffer)
+
+	err := derep.Run(
+		context.Background(), c, seqio.NewWriter(w, seqio.FASTQ),
+		derep.Options{Strand: derep.Both},
+	)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "@foo;size=5\nTTGA\n+\n+5?I\n", w.String(),
+			"Reverse complement should be merged in the first orientation.",
+		)
+	}
+}
+
+func TestRunStrandMerged(t *testing.T) {
+	seqs := []*linear.Seq{
+		linear.NewSeq("foo", []alphabet.Letter("TTGA"), alphabet.DNA),
+		linear.NewSeq("bar", []alphabet.Letter("TCAA"), alphabet.DNA),
+		linear.NewSeq("baz", []alphabet.Letter("TTGA"), alphabet.DNA),
+	}
+
+	c := make(chan seq.Sequence, len(seqs))
+
+	for _, s := range seqs {
+		c <- s
+	}
+
+	close(c)
+
+	var res *cluster.Cluster
+
+	err := derep.Run(
+		context.Background(), c,
+		writerFunc(func(s seq.Sequence) (int, error) {
+			res = s.(*cluster.Cluster)
+			return 0, nil
+		}),
+		derep.Options{Strand: derep.Both},
+	)
+
+	if assert.NoError(t, err) && assert.NotNil(t, res) {
+		var strands []seq.Strand
+
+		for _, a := range res.Merged {
+			strands = append(strands, a.Strand)
+		}
+
+		assert.Equal(t, []seq.Strand{seq.Plus, seq.Minus, seq.Plus}, strands,
+			"Sequences merged in reverse orientation should be marked.",
+		)
+		assert.Equal(t, seq.Plus, seqs[1].Strand,
+			"Input sequences should not be modified.",
+		)
+		assert.Equal(t, "TCAA", seqs[1].String(),
+			"Input sequences should not be modified.",
+		)
+	}
+}
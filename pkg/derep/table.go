@@ -0,0 +1,71 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package derep
+
+import (
+	"github.com/mys721tx/gsearch/pkg/cluster"
+)
+
+// table holds the clusters of unique sequences.
+type table interface {
+	// add merges a cluster into the cluster of the same key, or holds it as a
+	// new cluster and returns true.
+	add(c *cluster.Cluster) bool
+	// clusters returns the clusters in the order of their first sequence.
+	clusters() []*cluster.Cluster
+}
+
+// newTable returns the table of the options.
+func newTable(opt Options) table {
+	if opt.LowMemory {
+		return newHashTable(opt)
+	}
+
+	return &mapTable{
+		rep: make(map[string]*cluster.Cluster),
+		opt: opt,
+	}
+}
+
+// mapTable is a table keyed by the sequences as strings.
+type mapTable struct {
+	rep map[string]*cluster.Cluster
+	// uniq holds the clusters in the order of their first sequence.
+	uniq []*cluster.Cluster
+	opt  Options
+}
+
+func (t *mapTable) add(c *cluster.Cluster) bool {
+	k := Key(&c.Seq, t.opt.Strand)
+
+	r, prs := t.rep[k]
+
+	if !prs {
+		t.rep[k] = c
+		t.uniq = append(t.uniq, c)
+		return true
+	}
+
+	orient(r, c)
+	r.Merge(c, t.opt.Policies)
+
+	return false
+}
+
+func (t *mapTable) clusters() []*cluster.Cluster {
+	return t.uniq
+}
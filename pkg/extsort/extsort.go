@@ -0,0 +1,374 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package extsort provides an external sort of clusters, which spills sorted
+// runs to temporary files and merges them, for inputs larger than memory.
+package extsort
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/biogo/biogo/alphabet"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+)
+
+// Overhead is the estimated memory of a Record besides its strings and
+// slices.
+const Overhead = 256
+
+// Record is a cluster with its sort keys.
+type Record struct {
+	// Key is the key of the cluster, such as the sequence compared by
+	// dereplication.
+	Key string
+	// Order is the position of the first sequence of the cluster in the
+	// input.
+	Order int64
+	*cluster.Cluster
+}
+
+// Footprint returns the estimated memory of a record in bytes.
+func Footprint(r *Record) int64 {
+	n := Overhead + len(r.Key) + len(r.ID) + len(r.Desc) + len(r.Seq.Seq) +
+		len(r.Qual)
+
+	for _, a := range r.Attrs {
+		n += len(a.Key) + len(a.Value)
+	}
+
+	return int64(n)
+}
+
+// Less reports whether record a sorts before record b.
+type Less func(a, b *Record) bool
+
+// ByKey sorts records by Key, then by Order.
+func ByKey(a, b *Record) bool {
+	if a.Key != b.Key {
+		return a.Key < b.Key
+	}
+	return a.Order < b.Order
+}
+
+// ByOrder sorts records by Order.
+func ByOrder(a, b *Record) bool {
+	return a.Order < b.Order
+}
+
+// ByAbundance sorts records as cluster.ByAbundance, then by Order.
+func ByAbundance(a, b *Record) bool {
+	return byClusters(cluster.ByAbundance{a.Cluster, b.Cluster}, a, b)
+}
+
+// ByLength sorts records as cluster.ByLength, then by Order.
+func ByLength(a, b *Record) bool {
+	return byClusters(cluster.ByLength{a.Cluster, b.Cluster}, a, b)
+}
+
+// BySequence sorts records as cluster.BySequence, then by Order.
+func BySequence(a, b *Record) bool {
+	return byClusters(cluster.BySequence{a.Cluster, b.Cluster}, a, b)
+}
+
+// byClusters sorts the clusters of records a and b, held in l in this order,
+// then by Order.
+func byClusters(l sort.Interface, a, b *Record) bool {
+	switch {
+	case l.Less(0, 1):
+		return true
+	case l.Less(1, 0):
+		return false
+	}
+
+	return a.Order < b.Order
+}
+
+// ParseSize parses a size in bytes, such as "1048576", "512M" or "64G". The
+// suffixes K, M, G and T are powers of 1024.
+func ParseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("malformed size %q", s)
+	}
+
+	shift := uint(0)
+
+	switch strings.ToUpper(s[len(s)-1:]) {
+	case "K":
+		shift = 10
+	case "M":
+		shift = 20
+	case "G":
+		shift = 30
+	case "T":
+		shift = 40
+	}
+
+	num := s
+
+	if shift > 0 {
+		num = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseInt(num, 10, 64)
+
+	if err != nil || n < 0 || n > (1<<62)>>shift {
+		return 0, fmt.Errorf("malformed size %q", s)
+	}
+
+	return n << shift, nil
+}
+
+// Sorter sorts records by a Less function.
+//
+// The records are held in memory until their footprint reaches the limit,
+// when they are sorted and spilled to a temporary file as a run. Sort merges
+// the runs and the records in memory.
+type Sorter struct {
+	less  Less
+	limit int64
+	dir   string
+
+	buf  []*Record
+	size int64
+	runs []*os.File
+
+	// alphas indexes the alphabets of the spilled records.
+	alphas []alphabet.Alphabet
+}
+
+// NewSorter returns a Sorter spilling runs to dir when the records in memory
+// reach limit bytes. A limit of 0 never spills automatically. An empty dir is
+// the default directory for temporary files.
+func NewSorter(less Less, limit int64, dir string) *Sorter {
+	return &Sorter{less: less, limit: limit, dir: dir}
+}
+
+// Add adds a record to the Sorter, and spills the records in memory if they
+// reach the limit.
+func (s *Sorter) Add(r *Record) error {
+	s.buf = append(s.buf, r)
+	s.size += Footprint(r)
+
+	if s.limit > 0 && s.size >= s.limit {
+		return s.Spill()
+	}
+
+	return nil
+}
+
+// Runs returns the number of runs spilled.
+func (s *Sorter) Runs() int {
+	return len(s.runs)
+}
+
+// Spill sorts the records in memory and writes them to a temporary file as a
+// run.
+func (s *Sorter) Spill() error {
+	if len(s.buf) == 0 {
+		return nil
+	}
+
+	s.sortBuf()
+
+	f, err := ioutil.TempFile(s.dir, "gsearch-run-")
+
+	if err != nil {
+		return err
+	}
+
+	s.runs = append(s.runs, f)
+
+	w := bufio.NewWriter(f)
+
+	for _, r := range s.buf {
+		if err := s.writeRecord(w, r); err != nil {
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	s.buf, s.size = nil, 0
+
+	return nil
+}
+
+// sortBuf sorts the records in memory. Equal records keep their order.
+func (s *Sorter) sortBuf() {
+	sort.SliceStable(s.buf, func(i, j int) bool {
+		return s.less(s.buf[i], s.buf[j])
+	})
+}
+
+// Sort returns an Iterator of all the records in order. Records that are
+// equal by the Less function are returned in the order they were added. No
+// record should be added after Sort.
+func (s *Sorter) Sort() (*Iterator, error) {
+	s.sortBuf()
+
+	it := Iterator{less: s.less}
+
+	for i, f := range s.runs {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		src := &source{
+			next: s.runReader(bufio.NewReader(f)),
+			rank: i,
+		}
+
+		if err := it.push(src); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := s.buf
+
+	src := &source{
+		next: func() (*Record, error) {
+			if len(buf) == 0 {
+				return nil, io.EOF
+			}
+			r := buf[0]
+			buf = buf[1:]
+			return r, nil
+		},
+		rank: len(s.runs),
+	}
+
+	if err := it.push(src); err != nil {
+		return nil, err
+	}
+
+	s.buf, s.size = nil, 0
+
+	return &it, nil
+}
+
+// runReader returns a function reading the records of a run.
+func (s *Sorter) runReader(r *bufio.Reader) func() (*Record, error) {
+	return func() (*Record, error) {
+		return s.readRecord(r)
+	}
+}
+
+// Close removes the runs of the Sorter.
+func (s *Sorter) Close() error {
+	var res error
+
+	for _, f := range s.runs {
+		if err := f.Close(); err != nil && res == nil {
+			res = err
+		}
+
+		if err := os.Remove(f.Name()); err != nil && res == nil {
+			res = err
+		}
+	}
+
+	s.runs = nil
+
+	return res
+}
+
+// source is a sorted stream of records merged by an Iterator.
+type source struct {
+	next func() (*Record, error)
+	head *Record
+	// rank breaks the ties of sources in the order of their records added.
+	rank int
+}
+
+// Iterator returns the records of a Sorter in order.
+type Iterator struct {
+	less Less
+	srcs []*source
+}
+
+// push reads the head of a source and pushes it to the heap unless the source
+// is exhausted.
+func (it *Iterator) push(src *source) error {
+	r, err := src.next()
+
+	switch err {
+	case nil:
+		src.head = r
+		heap.Push(it, src)
+		return nil
+	case io.EOF:
+		return nil
+	}
+
+	return err
+}
+
+// Next returns the next record, or io.EOF after the last record.
+func (it *Iterator) Next() (*Record, error) {
+	if len(it.srcs) == 0 {
+		return nil, io.EOF
+	}
+
+	src := heap.Pop(it).(*source)
+	r := src.head
+
+	if err := it.push(src); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Len implements heap.Interface.
+func (it *Iterator) Len() int { return len(it.srcs) }
+
+// Less implements heap.Interface.
+func (it *Iterator) Less(i, j int) bool {
+	a, b := it.srcs[i], it.srcs[j]
+
+	switch {
+	case it.less(a.head, b.head):
+		return true
+	case it.less(b.head, a.head):
+		return false
+	}
+
+	return a.rank < b.rank
+}
+
+// Swap implements heap.Interface.
+func (it *Iterator) Swap(i, j int) { it.srcs[i], it.srcs[j] = it.srcs[j], it.srcs[i] }
+
+// Push implements heap.Interface.
+func (it *Iterator) Push(x interface{}) { it.srcs = append(it.srcs, x.(*source)) }
+
+// Pop implements heap.Interface.
+func (it *Iterator) Pop() interface{} {
+	src := it.srcs[len(it.srcs)-1]
+	it.srcs = it.srcs[:len(it.srcs)-1]
+	return src
+}
@@ -0,0 +1,192 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package extsort_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+	"github.com/mys721tx/gsearch/pkg/extsort"
+)
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"0":    0,
+		"1024": 1024,
+		"2k":   2 << 10,
+		"512M": 512 << 20,
+		"64G":  64 << 30,
+		"1T":   1 << 40,
+	}
+
+	for s, v := range cases {
+		n, err := extsort.ParseSize(s)
+
+		if assert.NoError(t, err, "%q should be parsed.", s) {
+			assert.Equal(t, v, n, "%q should be parsed in bytes.", s)
+		}
+	}
+
+	for _, s := range []string{"", "G", "-1", "1.5G", "1P", "9999999T"} {
+		_, err := extsort.ParseSize(s)
+		assert.Error(t, err, "%q should not be parsed.", s)
+	}
+}
+
+func newRecord(key string, order int64) *extsort.Record {
+	c := cluster.ParseAnno(linear.NewSeq(
+		key+";sample=A;size=2", []alphabet.Letter(key), alphabet.DNA,
+	))
+
+	c.Merged = nil
+
+	return &extsort.Record{Key: key, Order: order, Cluster: c}
+}
+
+func drain(t *testing.T, s *extsort.Sorter) []*extsort.Record {
+	it, err := s.Sort()
+
+	if !assert.NoError(t, err) {
+		return nil
+	}
+
+	var res []*extsort.Record
+
+	for {
+		r, err := it.Next()
+
+		if err == io.EOF {
+			return res
+		} else if !assert.NoError(t, err) {
+			return res
+		}
+
+		res = append(res, r)
+	}
+}
+
+func TestSorter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extsort")
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer os.RemoveAll(dir)
+
+	s := extsort.NewSorter(extsort.ByKey, 1, dir)
+
+	keys := []string{"GG", "AC", "TT", "AC", "CA", "GG"}
+
+	for i, k := range keys {
+		assert.NoError(t, s.Add(newRecord(k, int64(i))))
+	}
+
+	assert.Equal(t, len(keys), s.Runs(), "Each record should be spilled.")
+
+	res := drain(t, s)
+
+	var got []string
+	var orders []int64
+
+	for _, r := range res {
+		got = append(got, r.Key)
+		orders = append(orders, r.Order)
+	}
+
+	assert.Equal(t, []string{"AC", "AC", "CA", "GG", "GG", "TT"}, got,
+		"Records should be merged by their keys.",
+	)
+	assert.Equal(t, []int64{1, 3, 4, 0, 5, 2}, orders,
+		"Equal keys should be sorted by their orders.",
+	)
+
+	if assert.NotEmpty(t, res) {
+		r := res[0]
+		assert.Equal(t, "AC;sample=A;size=2", r.Name(), "The header should be kept.")
+		assert.Equal(t, "AC", r.String(), "The letters should be kept.")
+		assert.Equal(t, alphabet.DNA, r.Alpha, "The alphabet should be kept.")
+		assert.Nil(t, r.Qual, "A cluster without quality should be kept so.")
+	}
+
+	assert.NoError(t, s.Close())
+
+	files, _ := ioutil.ReadDir(dir)
+
+	assert.Empty(t, files, "The runs should be removed.")
+}
+
+func TestSorterQuality(t *testing.T) {
+	s := extsort.NewSorter(extsort.ByOrder, 0, "")
+
+	defer s.Close()
+
+	c := cluster.ParseAnno(linear.NewQSeq(
+		"foo;size=3",
+		[]alphabet.QLetter{{L: 'A', Q: 30}, {L: 'T', Q: 10}},
+		alphabet.DNAgapped,
+		alphabet.Illumina1_3,
+	))
+
+	c.Desc = "bar"
+
+	assert.NoError(t, s.Add(&extsort.Record{Key: "AT", Cluster: c}))
+	assert.NoError(t, s.Spill())
+
+	res := drain(t, s)
+
+	if assert.Len(t, res, 1) {
+		assert.Equal(t, c.Qual, res[0].Qual, "The quality should be kept.")
+		assert.Equal(t, c.Encode, res[0].Encode, "The encoding should be kept.")
+		assert.Equal(t, "bar", res[0].Desc, "The description should be kept.")
+		assert.Equal(t, alphabet.DNAgapped, res[0].Alpha,
+			"The alphabet should be kept.",
+		)
+	}
+}
+
+func TestSorterMemory(t *testing.T) {
+	s := extsort.NewSorter(extsort.ByAbundance, 0, "")
+
+	defer s.Close()
+
+	for i, size := range []int{1, 5, 3, 5} {
+		r := newRecord("AC", int64(i))
+		r.Size = size
+		assert.NoError(t, s.Add(r))
+	}
+
+	assert.Zero(t, s.Runs(), "A Sorter without limit should not spill.")
+
+	var orders []int64
+
+	for _, r := range drain(t, s) {
+		orders = append(orders, r.Order)
+	}
+
+	assert.Equal(t, []int64{1, 3, 2, 0}, orders,
+		"Records should be sorted by abundance, then by order.",
+	)
+}
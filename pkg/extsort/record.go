@@ -0,0 +1,194 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package extsort
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+)
+
+// A record is spilled as a sequence of fields. Integers are varints, and
+// strings and slices are prefixed by their length:
+//
+//	Key Order ID Desc Strand Alpha Letters Qual Encode Size Attrs
+//
+// Alpha is the index of the alphabet in the Sorter. Qual is prefixed by its
+// length plus one, so 0 marks a cluster without quality. Attrs is prefixed by
+// its count and holds the key and the value of each attribute.
+
+// alphaIndex returns the index of an alphabet, adding it if it is new.
+func (s *Sorter) alphaIndex(a alphabet.Alphabet) int {
+	for i, v := range s.alphas {
+		if v == a {
+			return i
+		}
+	}
+
+	s.alphas = append(s.alphas, a)
+
+	return len(s.alphas) - 1
+}
+
+// writeRecord writes a record to a run.
+func (s *Sorter) writeRecord(w *bufio.Writer, r *Record) error {
+	var buf [binary.MaxVarintLen64]byte
+
+	putInt := func(v int64) {
+		w.Write(buf[:binary.PutVarint(buf[:], v)])
+	}
+
+	putBytes := func(b []byte) {
+		putInt(int64(len(b)))
+		w.Write(b)
+	}
+
+	putString := func(v string) {
+		putInt(int64(len(v)))
+		w.WriteString(v)
+	}
+
+	putString(r.Key)
+	putInt(r.Order)
+	putString(r.ID)
+	putString(r.Desc)
+	putInt(int64(r.Strand))
+	putInt(int64(s.alphaIndex(r.Alpha)))
+	putBytes(alphabet.LettersToBytes(r.Seq.Seq))
+
+	if r.Qual == nil {
+		putInt(0)
+	} else {
+		putInt(int64(len(r.Qual)) + 1)
+
+		for _, q := range r.Qual {
+			w.WriteByte(byte(q))
+		}
+	}
+
+	putInt(int64(r.Encode))
+	putInt(int64(r.Size))
+	putInt(int64(len(r.Attrs)))
+
+	for _, a := range r.Attrs {
+		putString(a.Key)
+		putString(a.Value)
+	}
+
+	// The errors of bufio.Writer are sticky.
+	_, err := w.Write(nil)
+
+	return err
+}
+
+// recordReader reads the fields of a record, keeping the first error.
+type recordReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+// int reads a varint.
+func (rr *recordReader) int() int64 {
+	if rr.err != nil {
+		return 0
+	}
+
+	v, err := binary.ReadVarint(rr.r)
+	rr.err = err
+
+	return v
+}
+
+// bytes reads n bytes.
+func (rr *recordReader) bytes(n int64) []byte {
+	if rr.err != nil || n < 0 {
+		return nil
+	}
+
+	b := make([]byte, n)
+	_, rr.err = io.ReadFull(rr.r, b)
+
+	return b
+}
+
+// string reads a string prefixed by its length.
+func (rr *recordReader) string() string {
+	return string(rr.bytes(rr.int()))
+}
+
+// readRecord reads a record from a run. It returns io.EOF at the end of the
+// run.
+func (s *Sorter) readRecord(r *bufio.Reader) (*Record, error) {
+	if _, err := r.Peek(1); err != nil {
+		return nil, err
+	}
+
+	rr := recordReader{r: r}
+
+	res := Record{
+		Key:     rr.string(),
+		Order:   rr.int(),
+		Cluster: new(cluster.Cluster),
+	}
+
+	res.Seq = linear.Seq{
+		Annotation: seq.Annotation{
+			ID:     rr.string(),
+			Desc:   rr.string(),
+			Strand: seq.Strand(rr.int()),
+		},
+	}
+
+	if i := rr.int(); rr.err == nil {
+		res.Alpha = s.alphas[i]
+	}
+
+	res.Seq.Seq = alphabet.BytesToLetters(rr.bytes(rr.int()))
+
+	if n := rr.int(); n > 0 {
+		b := rr.bytes(n - 1)
+		res.Qual = make([]alphabet.Qphred, len(b))
+
+		for i, q := range b {
+			res.Qual[i] = alphabet.Qphred(q)
+		}
+	}
+
+	res.Encode = alphabet.Encoding(rr.int())
+	res.Size = int(rr.int())
+
+	for n := rr.int(); n > 0 && rr.err == nil; n-- {
+		res.Attrs = append(res.Attrs, cluster.Attr{
+			Key:   rr.string(),
+			Value: rr.string(),
+		})
+	}
+
+	if rr.err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if rr.err != nil {
+		return nil, rr.err
+	}
+
+	return &res, nil
+}
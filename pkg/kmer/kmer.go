@@ -0,0 +1,369 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package kmer provides an index of the unique k-mers, or words, of nucleotide
+// or protein sequences, which ranks the sequences sharing the most words with a
+// query as the candidates to align, as the prefilter of vsearch.
+package kmer
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/feat"
+)
+
+// DefaultK is the default length of the words, as vsearch.
+const DefaultK = 8
+
+// MaxK is the maximal length of the words.
+//
+// The postings of the words are held in a slice of a header for each possible
+// word, 24 bytes each, up to maxDense words, which is k = 9. A larger k holds
+// the postings in a map of the words seen instead, since the slice of k = 12
+// would take 400 MB before any sequence is inserted.
+const MaxK = 12
+
+// maxDense is the maximal number of possible words whose postings are held in
+// a slice.
+const maxDense = 1 << 18
+
+// DefaultProteinK is the default length of the words of proteins. The 20
+// amino acids make words of 4 about as specific as words of 8 nucleotides.
+const DefaultProteinK = 4
+
+// MaxProteinK is the maximal length of the words of proteins. Words longer
+// than 4 have more possible words than maxDense, so their postings are held in
+// a map as those of a long MaxK.
+const MaxProteinK = 5
+
+// The default limits of Search, as vsearch.
+const (
+	DefaultMaxAccepts = 1
+	DefaultMaxRejects = 32
+)
+
+// coding is the code of the letters of the words of a molecule. A word is
+// the number whose digits are the codes of its letters, in base radix.
+type coding struct {
+	// code is the digit of each letter, regardless of its case. Other
+	// letters are 0xff.
+	code  [256]byte
+	radix uint32
+	// max is the maximal length of the words.
+	max int
+	err error
+}
+
+// newCoding returns the coding of the letters.
+func newCoding(letters string, max int, err error) *coding {
+	c := &coding{radix: uint32(len(letters)), max: max, err: err}
+
+	for i := range c.code {
+		c.code[i] = 0xff
+	}
+
+	for i, l := range letters {
+		c.code[l], c.code[l+'a'-'A'] = byte(i), byte(i)
+	}
+
+	return c
+}
+
+var (
+	errK        = errors.New("kmer: word length should be between 1 and 12")
+	errProteinK = errors.New("kmer: word length of proteins should be between 1 and 5")
+)
+
+// nucleotides is the 2-bit code of the nucleotides, where U is T.
+var nucleotides = func() *coding {
+	c := newCoding("ACGT", MaxK, errK)
+	c.code['U'], c.code['u'] = c.code['T'], c.code['T']
+	return c
+}()
+
+// aminoAcids is the code of the 20 standard amino acids.
+var aminoAcids = newCoding("ACDEFGHIKLMNPQRSTVWY", MaxProteinK, errProteinK)
+
+// codingOf returns the coding of a molecule. RNA is coded as DNA.
+func codingOf(mol feat.Moltype) *coding {
+	if mol == feat.Protein {
+		return aminoAcids
+	}
+	return nucleotides
+}
+
+// size returns the number of words of length k.
+func (c *coding) size(k int) int {
+	n := 1
+
+	for i := 0; i < k; i++ {
+		n *= int(c.radix)
+	}
+
+	return n
+}
+
+// words returns the unique words of length k in the letters in increasing
+// order. Words with letters out of the coding are skipped.
+func (c *coding) words(l alphabet.Letters, k int) []uint32 {
+	var res []uint32
+
+	size := uint32(c.size(k))
+
+	var w uint32
+
+	// n is the number of valid letters ending at the current position.
+	n := 0
+
+	for _, v := range l {
+		d := c.code[v]
+
+		if d == 0xff {
+			n = 0
+			continue
+		}
+
+		w = (w*c.radix + uint32(d)) % size
+		n++
+
+		if n >= k {
+			res = append(res, w)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+
+	uniq := res[:0]
+
+	for i, w := range res {
+		if i == 0 || w != res[i-1] {
+			uniq = append(uniq, w)
+		}
+	}
+
+	return uniq
+}
+
+// postings holds the sequences having each word, in a slice of every possible
+// word or in a map of the words seen.
+type postings struct {
+	dense  [][]uint32
+	sparse map[uint32][]uint32
+}
+
+// newPostings returns the postings of n possible words.
+func newPostings(n int) postings {
+	if n > maxDense {
+		return postings{sparse: make(map[uint32][]uint32)}
+	}
+
+	return postings{dense: make([][]uint32, n)}
+}
+
+// add adds a sequence to the postings of a word.
+func (p *postings) add(w, id uint32) {
+	if p.sparse != nil {
+		p.sparse[w] = append(p.sparse[w], id)
+		return
+	}
+
+	p.dense[w] = append(p.dense[w], id)
+}
+
+// of returns the sequences having a word.
+func (p *postings) of(w uint32) []uint32 {
+	if p.sparse != nil {
+		return p.sparse[w]
+	}
+
+	return p.dense[w]
+}
+
+// Words returns the unique words of length k in the letters in increasing
+// order. A word is the 2-bit code of its nucleotides; words with letters other
+// than A, C, G, T and U are skipped.
+func Words(l alphabet.Letters, k int) []uint32 {
+	return nucleotides.words(l, k)
+}
+
+// WordsOf returns the unique words of length k in the letters of a molecule in
+// increasing order, as Words. The words of proteins are coded in base 20;
+// words with letters other than the 20 standard amino acids are skipped.
+func WordsOf(l alphabet.Letters, k int, mol feat.Moltype) []uint32 {
+	return codingOf(mol).words(l, k)
+}
+
+// Index holds the unique words of sequences for the search of candidates.
+//
+// Insert must not be called concurrently with other methods. Candidates and
+// Search are safe for concurrent use.
+type Index struct {
+	k int
+	c *coding
+	// postings holds the sequences having each word.
+	postings postings
+	// words holds the number of unique words of each sequence.
+	words []int
+	// counts pools the pointers to the slices counting shared words, so Put
+	// does not allocate.
+	counts sync.Pool
+}
+
+// NewIndex returns an empty Index of words of length k of nucleotides.
+func NewIndex(k int) (*Index, error) {
+	return NewIndexOf(k, feat.DNA)
+}
+
+// NewIndexOf returns an empty Index of words of length k of a molecule, as
+// WordsOf. The length is at most MaxK, or MaxProteinK for proteins.
+func NewIndexOf(k int, mol feat.Moltype) (*Index, error) {
+	c := codingOf(mol)
+
+	if k < 1 || k > c.max {
+		return nil, c.err
+	}
+
+	return &Index{k: k, c: c, postings: newPostings(c.size(k))}, nil
+}
+
+// K returns the length of the words.
+func (ix *Index) K() int {
+	return ix.k
+}
+
+// Len returns the number of sequences in the Index.
+func (ix *Index) Len() int {
+	return len(ix.words)
+}
+
+// Insert adds a sequence to the Index and returns its ID, which is the number
+// of sequences inserted before it.
+func (ix *Index) Insert(l alphabet.Letters) int {
+	id := len(ix.words)
+
+	words := ix.c.words(l, ix.k)
+
+	for _, w := range words {
+		ix.postings.add(w, uint32(id))
+	}
+
+	ix.words = append(ix.words, len(words))
+
+	return id
+}
+
+// Words returns the number of unique words of a sequence in the Index.
+func (ix *Index) Words(id int) int {
+	return ix.words[id]
+}
+
+// Candidate is a sequence of the Index sharing words with a query.
+type Candidate struct {
+	ID     int
+	Shared int
+}
+
+// Candidates returns the sequences sharing at least one word with a query,
+// ranked by the number of shared words, then by their IDs.
+//
+// If the query has no word, such as a query shorter than the words, all the
+// sequences are returned in the order of their IDs, as they cannot be ranked.
+func (ix *Index) Candidates(q alphabet.Letters) []Candidate {
+	words := ix.c.words(q, ix.k)
+
+	if len(words) == 0 {
+		res := make([]Candidate, len(ix.words))
+
+		for i := range res {
+			res[i].ID = i
+		}
+
+		return res
+	}
+
+	p, _ := ix.counts.Get().(*[]uint32)
+
+	if p == nil {
+		p = new([]uint32)
+	}
+
+	if len(*p) < len(ix.words) {
+		*p = make([]uint32, len(ix.words))
+	}
+
+	counts := *p
+
+	var touched []uint32
+
+	for _, w := range words {
+		for _, id := range ix.postings.of(w) {
+			if counts[id] == 0 {
+				touched = append(touched, id)
+			}
+			counts[id]++
+		}
+	}
+
+	res := make([]Candidate, len(touched))
+
+	for i, id := range touched {
+		res[i] = Candidate{ID: int(id), Shared: int(counts[id])}
+		counts[id] = 0
+	}
+
+	ix.counts.Put(p)
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Shared != res[j].Shared {
+			return res[i].Shared > res[j].Shared
+		}
+		return res[i].ID < res[j].ID
+	})
+
+	return res
+}
+
+// Search calls accept on the candidates of a query in their rank until
+// maxAccepts candidates are accepted or maxRejects candidates are rejected, as
+// the --maxaccepts and --maxrejects options of vsearch. A limit of 0 is
+// unlimited. Search returns the first error of accept.
+func (ix *Index) Search(q alphabet.Letters, maxAccepts, maxRejects int, accept func(Candidate) (bool, error)) error {
+	accepts, rejects := 0, 0
+
+	for _, c := range ix.Candidates(q) {
+		ok, err := accept(c)
+
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			accepts++
+		} else {
+			rejects++
+		}
+
+		if (maxAccepts > 0 && accepts >= maxAccepts) ||
+			(maxRejects > 0 && rejects >= maxRejects) {
+			return nil
+		}
+	}
+
+	return nil
+}
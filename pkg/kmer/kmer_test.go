@@ -0,0 +1,220 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package kmer_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/feat"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/kmer"
+)
+
+func TestWords(t *testing.T) {
+	// A=0, C=1, G=2, T=3
+	assert.Equal(t, []uint32{0x1, 0x6, 0xb},
+		kmer.Words(alphabet.Letters("ACGT"), 2),
+		"Words should be the 2-bit codes of the letters.",
+	)
+	assert.Equal(t, []uint32{0x0},
+		kmer.Words(alphabet.Letters("aaaaa"), 3),
+		"Words should be unique and case insensitive.",
+	)
+	assert.Equal(t, []uint32{0x1, 0x6},
+		kmer.Words(alphabet.Letters("ACNACGNG"), 2),
+		"Words with other letters should be skipped.",
+	)
+	assert.Equal(t, kmer.Words(alphabet.Letters("ACGT"), 3),
+		kmer.Words(alphabet.Letters("ACGU"), 3),
+		"U should be read as T.",
+	)
+	assert.Empty(t, kmer.Words(alphabet.Letters("ACG"), 4),
+		"A sequence shorter than the words has no word.",
+	)
+}
+
+func TestWordsOf(t *testing.T) {
+	// A=0, C=1, D=2, ..., Y=19
+	assert.Equal(t, []uint32{1*20 + 2, 2*20 + 19},
+		kmer.WordsOf(alphabet.Letters("CDY"), 2, feat.Protein),
+		"Words of proteins should be the codes of the letters in base 20.",
+	)
+	assert.Equal(t, []uint32{19*20 + 19},
+		kmer.WordsOf(alphabet.Letters("yyXyy*"), 2, feat.Protein),
+		"Words with other letters should be skipped.",
+	)
+	assert.Equal(t, kmer.Words(alphabet.Letters("ACGU"), 3),
+		kmer.WordsOf(alphabet.Letters("ACGU"), 3, feat.RNA),
+		"Words of RNA should be the words of DNA.",
+	)
+}
+
+func TestNewIndex(t *testing.T) {
+	for _, k := range []int{0, kmer.MaxK + 1} {
+		_, err := kmer.NewIndex(k)
+		assert.Error(t, err, "Word length %d should return an error.", k)
+	}
+
+	for _, k := range []int{0, kmer.MaxProteinK + 1} {
+		_, err := kmer.NewIndexOf(k, feat.Protein)
+		assert.Error(t, err, "Word length %d of proteins should return an error.", k)
+	}
+}
+
+func TestIndexOfProtein(t *testing.T) {
+	ix, err := kmer.NewIndexOf(3, feat.Protein)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ix.Insert(alphabet.Letters("MKVLWAALLV"))
+	ix.Insert(alphabet.Letters("MKVLYAQRST"))
+
+	assert.Equal(t,
+		[]kmer.Candidate{{ID: 0, Shared: 6}, {ID: 1, Shared: 2}},
+		ix.Candidates(alphabet.Letters("MKVLWAAL")),
+		"Proteins should be ranked by shared words.",
+	)
+}
+
+func TestIndexMaxK(t *testing.T) {
+	ix, err := kmer.NewIndex(kmer.MaxK)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ix.Insert(alphabet.Letters("ACGTACGTACGTAC"))
+	ix.Insert(alphabet.Letters("TTTTTTTTTTTTTT"))
+
+	assert.Equal(t,
+		[]kmer.Candidate{{ID: 0, Shared: 2}},
+		ix.Candidates(alphabet.Letters("ACGTACGTACGTA")),
+		"Candidates should be found in the postings of long words.",
+	)
+}
+
+func newIndex(t *testing.T, seqs ...string) *kmer.Index {
+	ix, err := kmer.NewIndex(3)
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	for i, s := range seqs {
+		assert.Equal(t, i, ix.Insert(alphabet.Letters(s)),
+			"IDs should be in the order of insertion.",
+		)
+	}
+
+	return ix
+}
+
+func TestCandidates(t *testing.T) {
+	ix := newIndex(t, "AAAAAA", "ACGTACGT", "ACGTTTTT", "GGGGCC")
+
+	assert.Equal(t, 4, ix.Len())
+	assert.Equal(t, 4, ix.Words(1), "ACGTACGT has 4 unique words.")
+
+	assert.Equal(t,
+		[]kmer.Candidate{{ID: 1, Shared: 4}, {ID: 2, Shared: 2}},
+		ix.Candidates(alphabet.Letters("ACGTACG")),
+		"Candidates should be ranked by shared words.",
+	)
+	assert.Equal(t,
+		[]kmer.Candidate{{ID: 1, Shared: 1}, {ID: 2, Shared: 1}},
+		ix.Candidates(alphabet.Letters("ACG")),
+		"Ties should be ranked by IDs.",
+	)
+	assert.Equal(t,
+		[]kmer.Candidate{{ID: 0}, {ID: 1}, {ID: 2}, {ID: 3}},
+		ix.Candidates(alphabet.Letters("AC")),
+		"A query without words should return all sequences.",
+	)
+	assert.Empty(t, ix.Candidates(alphabet.Letters("TGATGA")),
+		"Sequences without shared words should not be candidates.",
+	)
+}
+
+func TestCandidatesConcurrent(t *testing.T) {
+	ix := newIndex(t, "AAAAAA", "ACGTACGT", "ACGTTTTT", "GGGGCC")
+
+	exp := ix.Candidates(alphabet.Letters("ACGTACG"))
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				assert.Equal(t, exp, ix.Candidates(alphabet.Letters("ACGTACG")))
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestSearch(t *testing.T) {
+	ix := newIndex(t, "ACGTAC", "ACGTAA", "ACGTTT", "ACGGGG", "ACGCCC")
+
+	q := alphabet.Letters("ACGTAC")
+
+	cases := []struct {
+		accepts, rejects int
+		accept           map[int]bool
+		exp              []int
+	}{
+		{1, 32, map[int]bool{0: true}, []int{0}},
+		{2, 32, map[int]bool{0: true, 2: true}, []int{0, 1, 2}},
+		{1, 2, map[int]bool{}, []int{0, 1}},
+		{0, 0, map[int]bool{}, []int{0, 1, 2, 3, 4}},
+	}
+
+	for _, c := range cases {
+		var seen []int
+
+		err := ix.Search(q, c.accepts, c.rejects, func(x kmer.Candidate) (bool, error) {
+			seen = append(seen, x.ID)
+			return c.accept[x.ID], nil
+		})
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, c.exp, seen,
+				"Search should stop at %d accepts or %d rejects.",
+				c.accepts, c.rejects,
+			)
+		}
+	}
+
+	errStop := errors.New("stop")
+
+	err := ix.Search(q, 0, 0, func(kmer.Candidate) (bool, error) {
+		return false, errStop
+	})
+
+	assert.Equal(t, errStop, err, "The error of accept should be returned.")
+}
@@ -0,0 +1,279 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pairwise
+
+import (
+	"fmt"
+
+	"github.com/biogo/biogo/align"
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/feat"
+)
+
+// negInf is the score of an impossible cell.
+const negInf = -int(^uint(0)>>1) / 2
+
+// The layers of the table of EndsFreeAligner, as the aligners of biogo.
+const (
+	diag = iota
+	up
+	left
+)
+
+// EndsFreeAligner is the ends-free aligner, which biogo does not provide. The
+// gaps before the first and after the last aligned letters of either sequence
+// are free, so the alignment spans from the start of one sequence to the end
+// of one sequence. A gap of length n scores GapOpen plus the gap scores of its
+// n letters in Matrix.
+//
+// Only the sequences of alphabet.Letters are aligned.
+type EndsFreeAligner align.Affine
+
+// feature is an aligned region of a sequence.
+type feature struct {
+	start, end int
+}
+
+func (f feature) Name() string           { return "" }
+func (f feature) Description() string    { return "" }
+func (f feature) Location() feat.Feature { return nil }
+func (f feature) Start() int             { return f.start }
+func (f feature) End() int               { return f.end }
+func (f feature) Len() int               { return f.end - f.start }
+
+// featPair is a pair of aligned regions with their score.
+type featPair struct {
+	a, b  feature
+	score int
+}
+
+func (fp *featPair) Features() [2]feat.Feature { return [2]feat.Feature{fp.a, fp.b} }
+func (fp *featPair) Score() int                { return fp.score }
+
+// String formats the pair as the pairs of the aligners of biogo.
+func (fp *featPair) String() string {
+	switch {
+	case fp.a.start == fp.a.end:
+		return fmt.Sprintf("-/[%d,%d)=%d", fp.b.start, fp.b.end, fp.score)
+	case fp.b.start == fp.b.end:
+		return fmt.Sprintf("[%d,%d)/-=%d", fp.a.start, fp.a.end, fp.score)
+	}
+	return fmt.Sprintf("[%d,%d)/[%d,%d)=%d",
+		fp.a.start, fp.a.end, fp.b.start, fp.b.end, fp.score,
+	)
+}
+
+// Align aligns a query to a reference. It returns an error if the scoring
+// matrix does not match the alphabet, or the sequence types or alphabets do
+// not match.
+func (a EndsFreeAligner) Align(reference, query align.AlphabetSlicer) ([]feat.Pair, error) {
+	alpha := reference.Alphabet()
+
+	switch {
+	case alpha == nil:
+		return nil, align.ErrNoAlphabet
+	case alpha != query.Alphabet():
+		return nil, align.ErrMismatchedAlphabets
+	case alpha.IndexOf(alpha.Gap()) != 0:
+		return nil, align.ErrNotGappedAlphabet
+	case len(a.Matrix) < alpha.Len():
+		return nil, align.ErrMatrixWrongSize{Size: len(a.Matrix), Len: alpha.Len()}
+	}
+
+	for _, row := range a.Matrix {
+		if len(row) != len(a.Matrix) {
+			return nil, align.ErrMatrixNotSquare
+		}
+	}
+
+	r, ok := reference.Slice().(alphabet.Letters)
+
+	if !ok {
+		return nil, align.ErrTypeNotHandled
+	}
+
+	q, ok := query.Slice().(alphabet.Letters)
+
+	if !ok {
+		return nil, align.ErrMismatchedTypes
+	}
+
+	index := alpha.LetterIndex()
+
+	for _, l := range [2]alphabet.Letters{r, q} {
+		for i, v := range l {
+			if index[v] < 0 {
+				return nil, fmt.Errorf("align: illegal letter %q at position %d", v, i)
+			}
+		}
+	}
+
+	return a.align(r, q, index), nil
+}
+
+// max2 returns the larger of two scores.
+func max2(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// align fills the table of the affine gaps of Gotoh and traces the best
+// alignment back from the last row or column.
+func (a EndsFreeAligner) align(r, q alphabet.Letters, index alphabet.Index) []feat.Pair {
+	m := a.Matrix
+	rows, cols := len(r)+1, len(q)+1
+
+	table := make([][3]int, rows*cols)
+
+	// The cells of the first row and column start the alignment, so they only
+	// lead to the diagonal.
+	for p := range table {
+		table[p] = [3]int{diag: 0, up: negInf, left: negInf}
+	}
+
+	// open returns the score of a gap opened after the diagonal of a cell.
+	open := func(i, j, g int) int {
+		if i == 0 || j == 0 {
+			return negInf
+		}
+		return table[i*cols+j][diag] + a.GapOpen + g
+	}
+
+	for i := 1; i < rows; i++ {
+		rv := index[r[i-1]]
+
+		for j := 1; j < cols; j++ {
+			qv := index[q[j-1]]
+			p := i*cols + j
+
+			t := table[p-cols-1]
+			table[p][diag] = max2(t[diag], max2(t[up], t[left])) + m[rv][qv]
+			table[p][up] = max2(open(i-1, j, m[rv][0]), table[p-cols][up]+m[rv][0])
+			table[p][left] = max2(open(i, j-1, m[0][qv]), table[p-1][left]+m[0][qv])
+		}
+	}
+
+	// The alignment ends at the best cell of the last row or column, the
+	// last cell first among ties.
+	i, j, l := rows-1, cols-1, diag
+	best := negInf
+
+	try := func(x, y int) {
+		for k, s := range table[x*cols+y] {
+			if s > best {
+				best, i, j, l = s, x, y, k
+			}
+		}
+	}
+
+	try(rows-1, cols-1)
+
+	for y := cols - 2; y >= 0; y-- {
+		try(rows-1, y)
+	}
+
+	for x := rows - 2; x >= 0; x-- {
+		try(x, cols-1)
+	}
+
+	// ops holds the layer and score of each column, from the last.
+	type op struct{ l, s int }
+
+	var ops []op
+
+	for i > 0 && j > 0 {
+		p := i*cols + j
+		s := table[p][l]
+
+		switch l {
+		case diag:
+			d := m[index[r[i-1]]][index[q[j-1]]]
+			ops = append(ops, op{diag, d})
+
+			t := table[p-cols-1]
+
+			switch s - d {
+			case t[diag]:
+				l = diag
+			case t[up]:
+				l = up
+			default:
+				l = left
+			}
+
+			i, j = i-1, j-1
+		case up:
+			g := m[index[r[i-1]]][0]
+
+			if s == open(i-1, j, g) {
+				ops = append(ops, op{up, g + a.GapOpen})
+				l = diag
+			} else {
+				ops = append(ops, op{up, g})
+			}
+
+			i--
+		case left:
+			g := m[0][index[q[j-1]]]
+
+			if s == open(i, j-1, g) {
+				ops = append(ops, op{left, g + a.GapOpen})
+				l = diag
+			} else {
+				ops = append(ops, op{left, g})
+			}
+
+			j--
+		}
+	}
+
+	if len(ops) == 0 {
+		return nil
+	}
+
+	// The alignment starts at the cell where the traceback stops.
+	var res []feat.Pair
+	var cur *featPair
+
+	prev := -1
+
+	for k := len(ops) - 1; k >= 0; k-- {
+		o := ops[k]
+
+		if o.l != prev {
+			cur = &featPair{a: feature{start: i, end: i}, b: feature{start: j, end: j}}
+			res = append(res, cur)
+			prev = o.l
+		}
+
+		if o.l != left {
+			i++
+			cur.a.end = i
+		}
+
+		if o.l != up {
+			j++
+			cur.b.end = j
+		}
+
+		cur.score += o.s
+	}
+
+	return res
+}
@@ -0,0 +1,98 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pairwise
+
+import "fmt"
+
+// IDDef is a definition of the identity, as the --iddef option of vsearch.
+type IDDef int
+
+const (
+	// CDHit is the identical columns over the length of the shorter
+	// sequence, as CD-HIT.
+	CDHit IDDef = iota
+	// EditDistance is the identical columns over all the columns.
+	EditDistance
+	// Internal is the identical columns over the columns without the terminal
+	// gaps, the default of vsearch.
+	Internal
+	// MBL is 1 minus the mismatches and the gap opens, terminal or not, over
+	// the length of the longer sequence, as the Marine Biological Laboratory.
+	MBL
+	// BLAST is the identical columns over all the columns, as BLAST. It is
+	// EditDistance for the global alignments.
+	BLAST
+)
+
+// ParseIDDef parses the number of an identity definition, from 0 to 4.
+func ParseIDDef(n int) (IDDef, error) {
+	if n < int(CDHit) || n > int(BLAST) {
+		return Internal, fmt.Errorf("unknown identity definition %d", n)
+	}
+	return IDDef(n), nil
+}
+
+// IdentityOf returns the identity of the alignment by a definition, from 0 to
+// 1.
+func (a *Alignment) IdentityOf(d IDDef) float64 {
+	switch d {
+	case CDHit:
+		n := a.TargetLen
+
+		if a.QueryLen < n {
+			n = a.QueryLen
+		}
+
+		return ratio(a.Matches, n)
+	case EditDistance, BLAST:
+		return ratio(a.Matches, len(a.Target))
+	case MBL:
+		n := a.TargetLen
+
+		if a.QueryLen > n {
+			n = a.QueryLen
+		}
+
+		if n == 0 {
+			return 0
+		}
+
+		// The gap opens include the terminal gaps.
+		opens := 0
+
+		var prev byte
+
+		for i := range a.Target {
+			if o := a.op(i); o != 'M' && o != prev {
+				opens++
+			}
+			prev = a.op(i)
+		}
+
+		return 1 - ratio(a.Mismatches+opens, n)
+	}
+
+	return a.Identity()
+}
+
+// ratio returns n over d, or 0 if d is 0.
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
@@ -0,0 +1,70 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pairwise_test
+
+import (
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/pairwise"
+)
+
+func TestParseIDDef(t *testing.T) {
+	for n := 0; n <= 4; n++ {
+		d, err := pairwise.ParseIDDef(n)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, pairwise.IDDef(n), d, "Definition should be its number.")
+		}
+	}
+
+	for _, n := range []int{-1, 5} {
+		_, err := pairwise.ParseIDDef(n)
+
+		assert.Error(t, err, "Unknown definition %d should return an error.", n)
+	}
+}
+
+func TestIdentityOf(t *testing.T) {
+	// 9 terminal and 3 inner gaps in the query, with 28 identical columns.
+	aln, err := pairwise.Align(
+		nw, alphabet.Letters(seqA), alphabet.Letters(seqA[9:20]+seqA[23:]),
+	)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for d, id := range map[pairwise.IDDef]float64{
+		pairwise.CDHit:        1,
+		pairwise.EditDistance: 0.7,
+		pairwise.Internal:     28.0 / 31,
+		pairwise.MBL:          0.95,
+		pairwise.BLAST:        0.7,
+	} {
+		assert.InDelta(t, id, aln.IdentityOf(d), 1e-9,
+			"Identity of definition %d should be the same as vsearch.", d,
+		)
+	}
+
+	assert.Equal(t, aln.Identity(), aln.IdentityOf(pairwise.Internal),
+		"Identity should be the internal definition.",
+	)
+}
@@ -0,0 +1,281 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pairwise
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/biogo/biogo/align"
+	"github.com/biogo/biogo/align/matrix"
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/feat"
+)
+
+// Matrix is a substitution matrix, whose scores are indexed by the letters of
+// its alphabet. The scores of the gaps are 0 until set by WithGap.
+type Matrix struct {
+	Alphabet alphabet.Alphabet
+	Scores   align.Linear
+}
+
+// Matrices are the built-in substitution matrices by their names.
+var Matrices = map[string]Matrix{
+	"NUC.4.4":  {alphabet.DNAredundant, matrix.NUC_4_4},
+	"BLOSUM45": {alphabet.Protein, matrix.BLOSUM45},
+	"BLOSUM50": {alphabet.Protein, matrix.BLOSUM50},
+	"BLOSUM62": {alphabet.Protein, matrix.BLOSUM62},
+	"BLOSUM80": {alphabet.Protein, matrix.BLOSUM80},
+	"BLOSUM90": {alphabet.Protein, matrix.BLOSUM90},
+	"PAM30":    {alphabet.Protein, matrix.PAM30},
+	"PAM70":    {alphabet.Protein, matrix.PAM70},
+	"PAM250":   {alphabet.Protein, matrix.PAM250},
+}
+
+// WithGap returns a copy of the scores with the gaps scored by gap.
+func (m Matrix) WithGap(gap int) align.Linear {
+	s := make(align.Linear, len(m.Scores))
+	g := m.Alphabet.IndexOf(m.Alphabet.Gap())
+
+	for i := range m.Scores {
+		s[i] = append([]int(nil), m.Scores[i]...)
+
+		for j := range s[i] {
+			if i == g || j == g {
+				s[i][j] = gap
+			}
+		}
+	}
+
+	s[g][g] = 0
+
+	return s
+}
+
+// Of returns the matrix over the alphabet of a molecule. The matrices of DNA
+// also score RNA, as U scores as T.
+func (m Matrix) Of(mol feat.Moltype) (Matrix, error) {
+	switch {
+	case m.Alphabet.Moltype() == mol:
+		return m, nil
+	case m.Alphabet == alphabet.DNAredundant && mol == feat.RNA:
+		// The letters of RNAredundant are in the order of DNAredundant.
+		return Matrix{Alphabet: alphabet.RNAredundant, Scores: m.Scores}, nil
+	}
+	return Matrix{}, fmt.Errorf(
+		"pairwise: matrix of %v cannot score %v", m.Alphabet.Moltype(), mol,
+	)
+}
+
+// iupac are the nucleotides of the IUPAC codes of DNAredundant, as bits of A,
+// C, G and T.
+var iupac = map[alphabet.Letter]uint{
+	'a': 1, 'c': 2, 'g': 4, 't': 8,
+	'm': 1 | 2, 'r': 1 | 4, 'w': 1 | 8, 's': 2 | 4, 'y': 2 | 8, 'k': 4 | 8,
+	'v': 1 | 2 | 4, 'h': 1 | 2 | 8, 'd': 1 | 4 | 8, 'b': 2 | 4 | 8,
+	'n': 1 | 2 | 4 | 8,
+}
+
+// IUPAC returns the matrix of the IUPAC codes over DNAredundant with partial
+// matches: two codes score the expected score of their nucleotides, from match
+// and mismatch, rounded to the nearest integer. A and R score
+// (match+mismatch)/2, as R is A or G.
+func IUPAC(match, mismatch int) Matrix {
+	a := alphabet.DNAredundant
+	n := a.Len()
+	s := make(align.Linear, n)
+
+	for i := range s {
+		s[i] = make([]int, n)
+		x := iupac[a.Letter(i)]
+
+		for j := range s[i] {
+			y := iupac[a.Letter(j)]
+
+			if x == 0 || y == 0 {
+				continue
+			}
+
+			p := float64(bits.OnesCount(x&y)) /
+				float64(bits.OnesCount(x)*bits.OnesCount(y))
+
+			s[i][j] = int(math.Round(p*float64(match) + (1-p)*float64(mismatch)))
+		}
+	}
+
+	return Matrix{Alphabet: a, Scores: s}
+}
+
+// LoadMatrix returns the built-in matrix of a name, regardless of its case, or
+// parses the matrix file of the path.
+func LoadMatrix(name string) (Matrix, error) {
+	if m, ok := Matrices[strings.ToUpper(name)]; ok {
+		return m, nil
+	}
+
+	f, err := os.Open(name)
+
+	if err != nil {
+		return Matrix{}, fmt.Errorf("pairwise: unknown matrix %q: %w", name, err)
+	}
+
+	defer f.Close()
+
+	return ParseMatrix(f)
+}
+
+// ParseMatrix parses a matrix in the format of NCBI: comments start with #,
+// the first line lists the letters of the columns, and each following line is
+// a letter and its scores.
+//
+// The matrix is over DNAredundant or RNAredundant if its letters are
+// nucleotides, or Protein otherwise. The letters of the alphabet out of the
+// file score as the lowest score of the file.
+func ParseMatrix(r io.Reader) (Matrix, error) {
+	var (
+		cols []alphabet.Letter
+		rows = make(map[alphabet.Letter][]int)
+		low  = math.MaxInt32
+	)
+
+	sc := bufio.NewScanner(r)
+
+	for ln := 1; sc.Scan(); ln++ {
+		line := sc.Text()
+
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+
+		switch {
+		case len(fields) == 0:
+			continue
+		case cols == nil:
+			for _, f := range fields {
+				if len(f) != 1 {
+					return Matrix{}, fmt.Errorf("pairwise: illegal letter %q at line %d", f, ln)
+				}
+				cols = append(cols, alphabet.Letter(f[0]))
+			}
+			continue
+		case len(fields[0]) != 1:
+			return Matrix{}, fmt.Errorf("pairwise: illegal letter %q at line %d", fields[0], ln)
+		case len(fields) != len(cols)+1:
+			return Matrix{}, fmt.Errorf(
+				"pairwise: %d scores for %d letters at line %d",
+				len(fields)-1, len(cols), ln,
+			)
+		}
+
+		l := alphabet.Letter(fields[0][0])
+
+		if _, ok := rows[l]; ok {
+			return Matrix{}, fmt.Errorf("pairwise: duplicate letter %q at line %d", l, ln)
+		}
+
+		row := make([]int, len(cols))
+
+		for i, f := range fields[1:] {
+			v, err := strconv.Atoi(f)
+
+			if err != nil {
+				return Matrix{}, fmt.Errorf("pairwise: illegal score at line %d: %w", ln, err)
+			}
+
+			if v < low {
+				low = v
+			}
+
+			row[i] = v
+		}
+
+		rows[l] = row
+	}
+
+	if err := sc.Err(); err != nil {
+		return Matrix{}, err
+	}
+
+	if cols == nil {
+		return Matrix{}, fmt.Errorf("pairwise: empty matrix")
+	}
+
+	for _, l := range cols {
+		if _, ok := rows[l]; !ok {
+			return Matrix{}, fmt.Errorf("pairwise: no scores of letter %q", l)
+		}
+	}
+
+	var alpha alphabet.Alphabet
+
+	for _, a := range []alphabet.Alphabet{
+		alphabet.DNAredundant, alphabet.RNAredundant, alphabet.Protein,
+	} {
+		if CheckLetters(a, cols) == nil {
+			alpha = a
+			break
+		}
+	}
+
+	if alpha == nil {
+		return Matrix{}, CheckLetters(alphabet.Protein, cols)
+	}
+
+	index := alpha.LetterIndex()
+	gap := index[alpha.Gap()]
+	s := make(align.Linear, alpha.Len())
+
+	for i := range s {
+		s[i] = make([]int, alpha.Len())
+
+		for j := range s[i] {
+			if i != gap && j != gap {
+				s[i][j] = low
+			}
+		}
+	}
+
+	for _, x := range cols {
+		for j, y := range cols {
+			s[index[x]][index[y]] = rows[x][j]
+		}
+	}
+
+	return Matrix{Alphabet: alpha, Scores: s}, nil
+}
+
+// CheckLetters returns an error of the first letter out of an alphabet. The
+// aligners of biogo panic on such letters.
+func CheckLetters(alpha alphabet.Alphabet, l alphabet.Letters) error {
+	index := alpha.LetterIndex()
+
+	for i, v := range l {
+		if index[v] < 0 {
+			return fmt.Errorf("pairwise: illegal letter %q at position %d", v, i)
+		}
+	}
+
+	return nil
+}
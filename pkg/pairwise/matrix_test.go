@@ -0,0 +1,150 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pairwise_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/feat"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/pairwise"
+)
+
+// score returns the score of two letters in m.
+func score(m pairwise.Matrix, x, y alphabet.Letter) int {
+	index := m.Alphabet.LetterIndex()
+	return m.Scores[index[x]][index[y]]
+}
+
+func TestIUPAC(t *testing.T) {
+	m := pairwise.IUPAC(2, -1)
+
+	for _, c := range []struct {
+		x, y alphabet.Letter
+		exp  int
+	}{
+		{'A', 'A', 2},
+		{'A', 'C', -1},
+		{'a', 'R', 1},
+		{'C', 'R', -1},
+		{'N', 'A', 0},
+		{'N', 'N', 0},
+		{'-', 'A', 0},
+	} {
+		assert.Equal(t, c.exp, score(m, c.x, c.y),
+			"%c and %c should score their expected score.", c.x, c.y,
+		)
+	}
+}
+
+func TestWithGap(t *testing.T) {
+	m := pairwise.IUPAC(2, -1)
+	s := m.WithGap(-3)
+
+	assert.Equal(t, 0, s[0][0], "Two gaps should score 0.")
+	assert.Equal(t, -3, s[0][1], "Gap should score the gap.")
+	assert.Equal(t, -3, s[1][0], "Gap should score the gap.")
+	assert.Equal(t, 0, m.Scores[0][1], "Matrix should not be changed.")
+}
+
+func TestLoadMatrix(t *testing.T) {
+	m, err := pairwise.LoadMatrix("blosum62")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, alphabet.Protein, m.Alphabet,
+			"BLOSUM62 should be over proteins.",
+		)
+		assert.Equal(t, 11, score(m, 'W', 'W'), "W and W should score 11.")
+	}
+
+	m, err = pairwise.LoadMatrix("NUC.4.4")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, score(m, 'A', 'R'), "A and R should score 1.")
+	}
+
+	_, err = pairwise.LoadMatrix("BLOSUM0")
+
+	assert.Error(t, err, "Unknown matrix should return an error.")
+}
+
+func TestParseMatrix(t *testing.T) {
+	m, err := pairwise.ParseMatrix(strings.NewReader(
+		"# A matrix of nucleotides.\n" +
+			"   A  C  G  T\n" +
+			"A  5 -4 -4 -4\n" +
+			"C -4  5 -4 -4\n" +
+			"G -4 -4  5 -4\n" +
+			"T -4 -4 -4  5  # Last row.\n",
+	))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, alphabet.DNAredundant, m.Alphabet,
+			"Nucleotides should be over DNAredundant.",
+		)
+		assert.Equal(t, 5, score(m, 'a', 'A'), "A and A should score 5.")
+		assert.Equal(t, -4, score(m, 'G', 'T'), "G and T should score -4.")
+		assert.Equal(t, -4, score(m, 'N', 'A'),
+			"Letter out of the file should score the lowest score.",
+		)
+		assert.Equal(t, 0, score(m, '-', 'A'), "Gap should score 0.")
+	}
+
+	m, err = pairwise.ParseMatrix(strings.NewReader(
+		"   W  *\nW 11 -4\n* -4  1\n",
+	))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, alphabet.Protein, m.Alphabet,
+			"Amino acids should be over Protein.",
+		)
+	}
+
+	for _, s := range []string{
+		"",
+		"  A C\nA 1 0\n",
+		"  A C\nA 1 0\nC 0\n",
+		"  A C\nA 1 0\nC 0 x\n",
+		"  A C\nA 1 0\nA 0 1\n",
+		"  A O\nA 1 0\nO 0 1\n",
+		"  T U\nT 1 0\nU 0 1\n",
+	} {
+		_, err := pairwise.ParseMatrix(strings.NewReader(s))
+
+		assert.Error(t, err, "Malformed matrix should return an error.")
+	}
+}
+
+func TestMatrixOf(t *testing.T) {
+	m, err := pairwise.IUPAC(2, -1).Of(feat.RNA)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, alphabet.RNAredundant, m.Alphabet,
+			"Matrix of DNA should score RNA over RNAredundant.",
+		)
+		assert.Equal(t, 2, score(m, 'U', 'U'), "U should score as T.")
+		assert.Equal(t, 1, score(m, 'U', 'Y'), "U should score as T.")
+	}
+
+	_, err = pairwise.Matrices["BLOSUM62"].Of(feat.DNA)
+
+	assert.Error(t, err, "Matrix of proteins should not score DNA.")
+}
@@ -0,0 +1,101 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pairwise
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/biogo/biogo/align"
+)
+
+// Mode is the way two sequences are aligned.
+type Mode int
+
+const (
+	// Global aligns the sequences over their full lengths.
+	Global Mode = iota
+	// Local aligns the most similar regions of the sequences.
+	Local
+	// Fitted aligns the full query to a region of the target, as the glocal
+	// alignment of an amplicon to its reference.
+	Fitted
+	// EndsFree aligns the sequences without penalizing the gaps at their ends,
+	// as the semi-global alignment of overlapping sequences.
+	EndsFree
+)
+
+// String returns the name of the mode.
+func (m Mode) String() string {
+	switch m {
+	case Global:
+		return "global"
+	case Local:
+		return "local"
+	case Fitted:
+		return "fitted"
+	case EndsFree:
+		return "ends-free"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// ParseMode parses the name of a mode.
+func ParseMode(name string) (Mode, error) {
+	switch name {
+	case "global":
+		return Global, nil
+	case "local":
+		return Local, nil
+	case "fitted", "glocal":
+		return Fitted, nil
+	case "ends-free", "semiglobal":
+		return EndsFree, nil
+	}
+	return Global, fmt.Errorf("unknown mode %q", name)
+}
+
+var errGapOpen = errors.New("pairwise: gap open requires affine gaps")
+
+// ModeAligner returns the aligner of a mode with a scoring matrix, of affine
+// gaps opened by gapOpen, or of linear gaps if affine is false. Linear gaps
+// cannot be opened by a non-zero gapOpen.
+func ModeAligner(mode Mode, m align.Linear, gapOpen int, affine bool) (align.Aligner, error) {
+	linear := !affine
+
+	if linear && gapOpen != 0 {
+		return nil, errGapOpen
+	}
+
+	switch {
+	case mode == Global && linear:
+		return align.NW(m), nil
+	case mode == Global:
+		return align.NWAffine{Matrix: m, GapOpen: gapOpen}, nil
+	case mode == Local && linear:
+		return align.SW(m), nil
+	case mode == Local:
+		return align.SWAffine{Matrix: m, GapOpen: gapOpen}, nil
+	case mode == Fitted && linear:
+		return align.Fitted(m), nil
+	case mode == Fitted:
+		return align.FittedAffine{Matrix: m, GapOpen: gapOpen}, nil
+	case mode == EndsFree:
+		return EndsFreeAligner{Matrix: m, GapOpen: gapOpen}, nil
+	}
+	return nil, fmt.Errorf("unknown mode %v", mode)
+}
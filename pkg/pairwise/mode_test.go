@@ -0,0 +1,153 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pairwise_test
+
+import (
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/pairwise"
+)
+
+func TestParseMode(t *testing.T) {
+	for name, exp := range map[string]pairwise.Mode{
+		"global":    pairwise.Global,
+		"local":     pairwise.Local,
+		"fitted":    pairwise.Fitted,
+		"ends-free": pairwise.EndsFree,
+	} {
+		m, err := pairwise.ParseMode(name)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, exp, m, "Mode should be parsed from its name.")
+			assert.Equal(t, name, m.String(), "Name should be the same.")
+		}
+	}
+
+	m, err := pairwise.ParseMode("glocal")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, pairwise.Fitted, m, "Glocal should be fitted.")
+	}
+
+	_, err = pairwise.ParseMode("overlap")
+
+	assert.Error(t, err, "Unknown mode should return an error.")
+}
+
+func TestModeAligner(t *testing.T) {
+	m := nw.Matrix
+
+	cases := []struct {
+		mode    pairwise.Mode
+		gapOpen int
+		affine  bool
+		a, b    string
+		// pos holds the positions of the letters aligned.
+		pos     [4]int
+		columns int
+		gaps    int
+		msg     string
+	}{
+		{
+			pairwise.Fitted, 0, false, seqA, seqA[10:30],
+			[4]int{11, 30, 1, 20}, 20, 0,
+			"Query should be fitted into the target.",
+		},
+		{
+			pairwise.Fitted, -10, true, seqA, seqA[10:30],
+			[4]int{11, 30, 1, 20}, 20, 0,
+			"Query should be fitted into the target with affine gaps.",
+		},
+		{
+			pairwise.Local, 0, false, "TTTTT" + seqA[:20] + "TTTTT", "CCCCC" + seqA[:20] + "CCCCC",
+			[4]int{6, 25, 6, 25}, 20, 0,
+			"Similar regions should be aligned locally.",
+		},
+		{
+			pairwise.Local, -10, true, "TTTTT" + seqA[:20] + "TTTTT", "CCCCC" + seqA[:20] + "CCCCC",
+			[4]int{6, 25, 6, 25}, 20, 0,
+			"Similar regions should be aligned locally with affine gaps.",
+		},
+		{
+			pairwise.EndsFree, 0, false, seqA[:30], seqA[15:],
+			[4]int{16, 30, 1, 15}, 15, 0,
+			"Overlap should be aligned without the end gaps.",
+		},
+		{
+			pairwise.EndsFree, pairwise.GapOpen, true, seqA, seqA[5:20] + seqA[23:35],
+			[4]int{6, 35, 1, 27}, 30, 3,
+			"Inner gaps should be aligned with the end gaps free.",
+		},
+		{
+			pairwise.Global, 0, false, seqA, seqA,
+			[4]int{1, 40, 1, 40}, 40, 0,
+			"Sequences should be aligned globally.",
+		},
+	}
+
+	for _, c := range cases {
+		aligner, err := pairwise.ModeAligner(c.mode, m, c.gapOpen, c.affine)
+
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		aln, err := pairwise.Align(aligner, alphabet.Letters(c.a), alphabet.Letters(c.b))
+
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		assert.Equal(t, c.pos,
+			[4]int{aln.TargetStart, aln.TargetEnd, aln.QueryStart, aln.QueryEnd},
+			c.msg,
+		)
+		assert.Equal(t, c.columns, aln.Columns, c.msg)
+		assert.Equal(t, c.gaps, aln.Gaps, c.msg)
+		assert.Equal(t, c.columns-c.gaps, aln.Matches, c.msg)
+	}
+
+	_, err := pairwise.ModeAligner(pairwise.Mode(-1), m, 0, false)
+
+	assert.Error(t, err, "Unknown mode should return an error.")
+
+	_, err = pairwise.ModeAligner(pairwise.Global, m, pairwise.GapOpen, false)
+
+	assert.Error(t, err, "Linear gaps should not be opened.")
+}
+
+func TestEndsFreeAligner(t *testing.T) {
+	aligner := pairwise.EndsFreeAligner{Matrix: nw.Matrix}
+
+	for _, c := range [][2]string{{"", seqA}, {seqA, ""}, {"AAAA", "CCCC"}} {
+		aln, err := pairwise.Align(aligner, alphabet.Letters(c[0]), alphabet.Letters(c[1]))
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0, aln.Columns,
+				"Sequences without similarity should not be aligned.",
+			)
+		}
+	}
+
+	_, err := pairwise.Align(aligner, alphabet.Letters("ACGT"), alphabet.Letters("AC*T"))
+
+	assert.Error(t, err, "Letters out of the alphabet should return an error.")
+}
@@ -0,0 +1,186 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pairwise
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/biogo/biogo/seq/linear"
+)
+
+// Format is an output format of alignments.
+type Format int
+
+const (
+	// SAM is the Sequence Alignment/Map format, with the queries as the reads
+	// and the targets as the references.
+	SAM Format = iota
+	// Blast6 is the BLAST tabular format, as the -outfmt 6 option of BLAST.
+	Blast6
+	// JSONL is the JSON Lines format, a JSON object of each alignment.
+	JSONL
+)
+
+// String returns the name of the format.
+func (f Format) String() string {
+	switch f {
+	case SAM:
+		return "sam"
+	case Blast6:
+		return "blast6"
+	case JSONL:
+		return "jsonl"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// ParseFormat parses the name of a format.
+func ParseFormat(name string) (Format, error) {
+	switch name {
+	case "sam":
+		return SAM, nil
+	case "blast6":
+		return Blast6, nil
+	case "jsonl":
+		return JSONL, nil
+	}
+	return SAM, fmt.Errorf("unknown format %q", name)
+}
+
+// Record is the JSON object of an alignment. The identity and the coverages
+// are in percent.
+type Record struct {
+	Query          string  `json:"query"`
+	Target         string  `json:"target"`
+	Score          int     `json:"score"`
+	CIGAR          string  `json:"cigar"`
+	Identity       float64 `json:"identity"`
+	Columns        int     `json:"alnlen"`
+	Matches        int     `json:"matches"`
+	Mismatches     int     `json:"mismatches"`
+	Gaps           int     `json:"gaps"`
+	GapOpens       int     `json:"gap_opens"`
+	QueryStart     int     `json:"qlo"`
+	QueryEnd       int     `json:"qhi"`
+	TargetStart    int     `json:"tlo"`
+	TargetEnd      int     `json:"thi"`
+	QueryLen       int     `json:"ql"`
+	TargetLen      int     `json:"tl"`
+	QueryCoverage  float64 `json:"qcov"`
+	TargetCoverage float64 `json:"tcov"`
+}
+
+// Writer writes the alignments of queries to targets in a format.
+type Writer struct {
+	w      io.Writer
+	format Format
+	def    IDDef
+	enc    *json.Encoder
+}
+
+// NewWriter returns a Writer of a format, with the identity of a definition.
+func NewWriter(w io.Writer, format Format, def IDDef) *Writer {
+	return &Writer{w: w, format: format, def: def, enc: json.NewEncoder(w)}
+}
+
+// WriteHeader writes the header of the targets: the @HD and @SQ lines of SAM.
+// Other formats have no header.
+func (w *Writer) WriteHeader(targets ...*linear.Seq) error {
+	if w.format != SAM {
+		return nil
+	}
+
+	if _, err := fmt.Fprint(w.w, "@HD\tVN:1.6\tSO:unsorted\n"); err != nil {
+		return err
+	}
+
+	for _, t := range targets {
+		if _, err := fmt.Fprintf(w.w, "@SQ\tSN:%s\tLN:%d\n", t.ID, t.Len()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write writes the alignment of a query to a target.
+func (w *Writer) Write(target, query *linear.Seq, a *Alignment) error {
+	var err error
+
+	switch w.format {
+	case SAM:
+		err = w.writeSAM(target, query, a)
+	case Blast6:
+		_, err = fmt.Fprintf(
+			w.w, "%s\t%s\t%.1f\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t-1\t0\n",
+			query.ID, target.ID, a.IdentityOf(w.def)*100, a.Columns,
+			a.Mismatches, a.GapOpens, a.QueryStart, a.QueryEnd,
+			a.TargetStart, a.TargetEnd,
+		)
+	case JSONL:
+		err = w.enc.Encode(Record{
+			Query:          query.ID,
+			Target:         target.ID,
+			Score:          a.Score,
+			CIGAR:          a.CIGAR(),
+			Identity:       a.IdentityOf(w.def) * 100,
+			Columns:        a.Columns,
+			Matches:        a.Matches,
+			Mismatches:     a.Mismatches,
+			Gaps:           a.Gaps,
+			GapOpens:       a.GapOpens,
+			QueryStart:     a.QueryStart,
+			QueryEnd:       a.QueryEnd,
+			TargetStart:    a.TargetStart,
+			TargetEnd:      a.TargetEnd,
+			QueryLen:       a.QueryLen,
+			TargetLen:      a.TargetLen,
+			QueryCoverage:  a.QueryCoverage() * 100,
+			TargetCoverage: a.TargetCoverage() * 100,
+		})
+	default:
+		err = fmt.Errorf("pairwise: unknown format %v", w.format)
+	}
+
+	return err
+}
+
+// writeSAM writes the alignment as a SAM line, or an unmapped line if no
+// column is aligned. The tags are the score as AS and the edit distance as
+// NM.
+func (w *Writer) writeSAM(target, query *linear.Seq, a *Alignment) error {
+	seq := query.Seq.String()
+
+	if seq == "" {
+		seq = "*"
+	}
+
+	if a.Columns == 0 {
+		_, err := fmt.Fprintf(w.w, "%s\t4\t*\t0\t0\t*\t*\t0\t0\t%s\t*\n", query.ID, seq)
+		return err
+	}
+
+	_, err := fmt.Fprintf(
+		w.w, "%s\t0\t%s\t%d\t255\t%s\t*\t0\t0\t%s\t*\tAS:i:%d\tNM:i:%d\n",
+		query.ID, target.ID, a.TargetStart, a.CIGAR(), seq,
+		a.Score, a.Mismatches+a.Gaps,
+	)
+
+	return err
+}
@@ -0,0 +1,137 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pairwise_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/pairwise"
+)
+
+func TestParseFormat(t *testing.T) {
+	for name, exp := range map[string]pairwise.Format{
+		"sam":    pairwise.SAM,
+		"blast6": pairwise.Blast6,
+		"jsonl":  pairwise.JSONL,
+	} {
+		f, err := pairwise.ParseFormat(name)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, exp, f, "Format should be parsed from its name.")
+			assert.Equal(t, name, f.String(), "Name should be the same.")
+		}
+	}
+
+	_, err := pairwise.ParseFormat("bam")
+
+	assert.Error(t, err, "Unknown format should return an error.")
+}
+
+// writeAlignment aligns a query to seqA and writes it in a format.
+func writeAlignment(t *testing.T, f pairwise.Format, letters string) string {
+	ref := linear.NewSeq("ref", []alphabet.Letter(seqA), alphabet.DNA)
+	q := linear.NewSeq("q", []alphabet.Letter(letters), alphabet.DNA)
+
+	aln, err := pairwise.Align(nw, ref.Seq, q.Seq)
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	b := new(bytes.Buffer)
+	w := pairwise.NewWriter(b, f, pairwise.Internal)
+
+	assert.NoError(t, w.WriteHeader(ref))
+	assert.NoError(t, w.Write(ref, q, aln))
+
+	return b.String()
+}
+
+func TestWriterSAM(t *testing.T) {
+	q := seqA[9:20] + seqA[23:]
+
+	assert.Equal(t,
+		"@HD\tVN:1.6\tSO:unsorted\n"+
+			"@SQ\tSN:ref\tLN:40\n"+
+			"q\t0\tref\t10\t255\t11M3D17M\t*\t0\t0\t"+q+"\t*\tAS:i:-8\tNM:i:3\n",
+		writeAlignment(t, pairwise.SAM, q),
+		"Alignment should be written as SAM.",
+	)
+
+	aln := &pairwise.Alignment{}
+	b := new(bytes.Buffer)
+
+	assert.NoError(t, pairwise.NewWriter(b, pairwise.SAM, pairwise.Internal).Write(
+		linear.NewSeq("ref", nil, alphabet.DNA),
+		linear.NewSeq("q", []alphabet.Letter("ACGT"), alphabet.DNA),
+		aln,
+	))
+
+	assert.Equal(t, "q\t4\t*\t0\t0\t*\t*\t0\t0\tACGT\t*\n", b.String(),
+		"Unaligned query should be written as unmapped.",
+	)
+}
+
+func TestWriterBlast6(t *testing.T) {
+	assert.Equal(t,
+		"q\tref\t97.5\t40\t1\t0\t1\t40\t1\t40\t-1\t0\n",
+		writeAlignment(t, pairwise.Blast6, seqB),
+		"Alignment should be written as BLAST tabular.",
+	)
+}
+
+func TestWriterJSONL(t *testing.T) {
+	var r pairwise.Record
+
+	if assert.NoError(t, json.Unmarshal(
+		[]byte(writeAlignment(t, pairwise.JSONL, seqA[9:20]+seqA[23:])), &r,
+	)) {
+		assert.Equal(t,
+			pairwise.Record{
+				Query:          "q",
+				Target:         "ref",
+				Score:          -8,
+				CIGAR:          "11M3D17M",
+				Identity:       r.Identity,
+				Columns:        31,
+				Matches:        28,
+				Gaps:           3,
+				GapOpens:       1,
+				QueryStart:     1,
+				QueryEnd:       28,
+				TargetStart:    10,
+				TargetEnd:      40,
+				QueryLen:       28,
+				TargetLen:      40,
+				QueryCoverage:  100,
+				TargetCoverage: 77.5,
+			},
+			r,
+			"Alignment should be written as a JSON object.",
+		)
+		assert.InDelta(t, 2800.0/31, r.Identity, 1e-9,
+			"Identity should be in percent.",
+		)
+	}
+}
@@ -0,0 +1,390 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package pairwise aligns pairs of nucleotide or protein sequences and
+// summarizes their alignments as vsearch: the identity, the mismatches, the
+// gaps and the compressed alignment.
+package pairwise
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/biogo/biogo/align"
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/seq/linear"
+)
+
+// The default scores of the aligner, as vsearch.
+const (
+	Match    = 2
+	Mismatch = -4
+	Gap      = -2
+	GapOpen  = -20
+)
+
+// The default gap scores of the aligner of proteins with BLOSUM62, the gap
+// costs of BLAST: 11 to open a gap and 1 to extend it.
+const (
+	ProteinGap     = -1
+	ProteinGapOpen = -10
+)
+
+// Alphabet is the alphabet of the sequences aligned.
+var Alphabet = alphabet.DNAredundant
+
+// AlphabetOf returns the alphabet of the sequences of a molecule aligned:
+// Alphabet for DNA, RNAredundant for RNA and Protein for proteins.
+func AlphabetOf(mol feat.Moltype) alphabet.Alphabet {
+	switch mol {
+	case feat.RNA:
+		return alphabet.RNAredundant
+	case feat.Protein:
+		return alphabet.Protein
+	}
+	return Alphabet
+}
+
+// NewAligner returns a global aligner of the scores over Alphabet. A gap of
+// length n scores gapOpen + n*gap.
+func NewAligner(match, mismatch, gap, gapOpen int) align.NWAffine {
+	n := Alphabet.Len()
+	m := make(align.Linear, n)
+
+	for i := range m {
+		m[i] = make([]int, n)
+
+		for j := range m[i] {
+			switch {
+			case i == 0 || j == 0:
+				m[i][j] = gap
+			case i == j:
+				m[i][j] = match
+			default:
+				m[i][j] = mismatch
+			}
+		}
+	}
+
+	m[0][0] = 0
+
+	return align.NWAffine{Matrix: m, GapOpen: gapOpen}
+}
+
+// NewAlignerOf returns a global aligner of the default scores of a molecule.
+// Nucleotides are scored by NewAligner of Match, Mismatch, Gap and GapOpen,
+// whose matrix also scores RNA. Proteins are scored by BLOSUM62 with
+// ProteinGap and ProteinGapOpen.
+func NewAlignerOf(mol feat.Moltype) align.NWAffine {
+	if mol == feat.Protein {
+		return align.NWAffine{
+			Matrix:  Matrices["BLOSUM62"].WithGap(ProteinGap),
+			GapOpen: ProteinGapOpen,
+		}
+	}
+
+	return NewAligner(Match, Mismatch, Gap, GapOpen)
+}
+
+// Alignment is the alignment of a query to a target.
+//
+// The counts exclude the terminal gaps, the gaps before the first and after
+// the last column where both sequences have a letter.
+type Alignment struct {
+	// Target and Query are the aligned letters, with gaps.
+	Target, Query alphabet.Letters
+	// Columns is the number of columns.
+	Columns int
+	// Matches and Mismatches are the numbers of columns of identical and
+	// different letters.
+	Matches, Mismatches int
+	// Gaps is the number of gap columns, and GapOpens the number of runs of
+	// them.
+	Gaps, GapOpens int
+	// TargetStart, TargetEnd, QueryStart and QueryEnd are the 1-based
+	// positions of the first and last letters aligned.
+	TargetStart, TargetEnd, QueryStart, QueryEnd int
+	// TargetLen and QueryLen are the lengths of the sequences.
+	TargetLen, QueryLen int
+	// Score is the score of the alignment.
+	Score int
+}
+
+// Align aligns a query to a target over Alphabet and returns their alignment.
+func Align(aligner align.Aligner, target, query alphabet.Letters) (*Alignment, error) {
+	return AlignOver(aligner, Alphabet, target, query)
+}
+
+// AlignOver aligns a query to a target over an alphabet, such as AlphabetOf a
+// molecule, and returns their alignment. The matrix of the aligner is indexed
+// by the letters of the alphabet.
+func AlignOver(aligner align.Aligner, alpha alphabet.Alphabet, target, query alphabet.Letters) (*Alignment, error) {
+	for _, l := range [2]alphabet.Letters{target, query} {
+		if err := CheckLetters(alpha, l); err != nil {
+			return nil, err
+		}
+	}
+
+	x := &linear.Seq{Seq: target}
+	y := &linear.Seq{Seq: query}
+
+	x.Alpha, y.Alpha = alpha, alpha
+
+	aln, err := aligner.Align(x, y)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAlignment(alpha, target, query, aln), nil
+}
+
+// NewAlignment returns the alignment of a query to a target over an alphabet
+// from the pairs returned by an aligner. The local aligners only align regions
+// of the sequences, and the letters out of the regions are not in
+// Alignment.Target and Alignment.Query.
+func NewAlignment(alpha alphabet.Alphabet, target, query alphabet.Letters, aln []feat.Pair) *Alignment {
+	x := &linear.Seq{Seq: target}
+	y := &linear.Seq{Seq: query}
+
+	x.Alpha, y.Alpha = alpha, alpha
+
+	f := align.Format(x, y, aln, alpha.Gap())
+
+	a := &Alignment{
+		Target:    f[0].(alphabet.Letters),
+		Query:     f[1].(alphabet.Letters),
+		TargetLen: len(target),
+		QueryLen:  len(query),
+	}
+
+	if len(aln) > 0 {
+		p := aln[0].Features()
+		a.TargetStart, a.QueryStart = p[0].Start(), p[1].Start()
+	}
+
+	for _, p := range aln {
+		if s, ok := p.(interface{ Score() int }); ok {
+			a.Score += s.Score()
+		}
+	}
+
+	a.count(alpha)
+
+	return a
+}
+
+// op returns the operation of column i: M for two letters, D for a gap in the
+// query or I for a gap in the target.
+func (a *Alignment) op(i int) byte {
+	gap := Alphabet.Gap()
+
+	switch {
+	case a.Query[i] == gap:
+		return 'D'
+	case a.Target[i] == gap:
+		return 'I'
+	}
+	return 'M'
+}
+
+// bounds returns the first column and the column after the last column
+// without terminal gaps.
+func (a *Alignment) bounds() (start, end int) {
+	start, end = 0, len(a.Target)
+
+	for start < end && a.op(start) != 'M' {
+		start++
+	}
+
+	for end > start && a.op(end-1) != 'M' {
+		end--
+	}
+
+	return start, end
+}
+
+// count counts the columns of the alignment, whose first letters follow
+// a.TargetStart and a.QueryStart letters of the sequences.
+func (a *Alignment) count(alpha alphabet.Alphabet) {
+	index := alpha.LetterIndex()
+	start, end := a.bounds()
+
+	for i := 0; i < start; i++ {
+		if a.op(i) == 'D' {
+			a.TargetStart++
+		} else {
+			a.QueryStart++
+		}
+	}
+
+	a.TargetEnd, a.QueryEnd = a.TargetStart, a.QueryStart
+	a.TargetStart++
+	a.QueryStart++
+
+	var prev byte
+
+	for i := start; i < end; i++ {
+		o := a.op(i)
+
+		if o != 'I' {
+			a.TargetEnd++
+		}
+
+		if o != 'D' {
+			a.QueryEnd++
+		}
+
+		switch {
+		case o != 'M':
+			if o != prev {
+				a.GapOpens++
+			}
+			a.Gaps++
+		case index[a.Target[i]] == index[a.Query[i]]:
+			a.Matches++
+		default:
+			a.Mismatches++
+		}
+
+		prev = o
+	}
+
+	a.Columns = end - start
+}
+
+// Identity returns the identity of the alignment, from 0 to 1: the number of
+// identical columns over the number of columns, as the --iddef 2 option of
+// vsearch.
+func (a *Alignment) Identity() float64 {
+	return ratio(a.Matches, a.Columns)
+}
+
+// Distance returns the edit distance of the alignment: the number of columns,
+// with the terminal gaps, whose letters are not identical.
+func (a *Alignment) Distance() int {
+	return len(a.Target) - a.Matches
+}
+
+// Compressed returns the compressed alignment of the UC format, with the
+// terminal gaps: each run of columns is its length and M for the columns of
+// two letters, D for a gap in the query or I for a gap in the target. A length
+// of 1 is omitted, and an alignment of identical sequences is "=".
+func (a *Alignment) Compressed() string {
+	if a.Matches == a.TargetLen && a.Matches == a.QueryLen {
+		return "="
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(a.Target); {
+		o, n := a.op(i), 1
+
+		for i+n < len(a.Target) && a.op(i+n) == o {
+			n++
+		}
+
+		if n > 1 {
+			b.WriteString(strconv.Itoa(n))
+		}
+
+		b.WriteByte(o)
+
+		i += n
+	}
+
+	return b.String()
+}
+
+// RevComp returns the reverse complement of the letters over Alphabet. The
+// letters out of Alphabet are kept.
+func RevComp(l alphabet.Letters) alphabet.Letters {
+	t := Alphabet.ComplementTable()
+	rc := make(alphabet.Letters, len(l))
+
+	for i, v := range l {
+		if c := t[v]; c != 0xff {
+			v = c
+		}
+		rc[len(rc)-1-i] = v
+	}
+
+	return rc
+}
+
+// Identical returns the alignment of identical letters without gaps. Its score
+// is 0, as the letters are not scored.
+func Identical(l alphabet.Letters) *Alignment {
+	return &Alignment{
+		Target:      l,
+		Query:       l,
+		Columns:     len(l),
+		Matches:     len(l),
+		TargetStart: 1,
+		TargetEnd:   len(l),
+		QueryStart:  1,
+		QueryEnd:    len(l),
+		TargetLen:   len(l),
+		QueryLen:    len(l),
+	}
+}
+
+// CIGAR returns the CIGAR string of the alignment in SAM: the columns without
+// the terminal gaps, with the letters of the query out of the alignment
+// clipped as S. It returns "*" if no column is aligned.
+func (a *Alignment) CIGAR() string {
+	start, end := a.bounds()
+
+	if start == end {
+		return "*"
+	}
+
+	var b strings.Builder
+
+	if a.QueryStart > 1 {
+		fmt.Fprintf(&b, "%dS", a.QueryStart-1)
+	}
+
+	for i := start; i < end; {
+		o, n := a.op(i), 1
+
+		for i+n < end && a.op(i+n) == o {
+			n++
+		}
+
+		fmt.Fprintf(&b, "%d%c", n, o)
+
+		i += n
+	}
+
+	if a.QueryEnd < a.QueryLen {
+		fmt.Fprintf(&b, "%dS", a.QueryLen-a.QueryEnd)
+	}
+
+	return b.String()
+}
+
+// QueryCoverage returns the fraction of the query aligned, from 0 to 1.
+func (a *Alignment) QueryCoverage() float64 {
+	return ratio(a.QueryEnd-a.QueryStart+1, a.QueryLen)
+}
+
+// TargetCoverage returns the fraction of the target aligned, from 0 to 1.
+func (a *Alignment) TargetCoverage() float64 {
+	return ratio(a.TargetEnd-a.TargetStart+1, a.TargetLen)
+}
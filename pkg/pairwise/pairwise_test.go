@@ -0,0 +1,198 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pairwise_test
+
+import (
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/feat"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/pairwise"
+)
+
+const (
+	seqA = "ACGTTGCAAGGCTTACCGATGCATGCAAGTCCTAGGATCA"
+	// seqB has 1 mismatch to seqA in 40 columns.
+	seqB = "ACGTTGCAAGGCTTACCGATCCATGCAAGTCCTAGGATCA"
+	// seqC has 4 mismatches to seqA in 40 columns.
+	seqC = "ACGTAGCAAGGCTAACCGATGCATGCTAGTCCTAGCATCA"
+)
+
+var nw = pairwise.NewAligner(
+	pairwise.Match, pairwise.Mismatch, pairwise.Gap, pairwise.GapOpen,
+)
+
+func TestIdentity(t *testing.T) {
+	cases := []struct {
+		a, b string
+		id   float64
+	}{
+		{seqA, seqA, 1},
+		{seqA, seqB, 0.975},
+		{seqA, seqC, 0.9},
+		{seqA, seqA[5:30], 1},
+		{"acgtn", "ACGTN", 1},
+	}
+
+	for _, c := range cases {
+		aln, err := pairwise.Align(
+			nw, alphabet.Letters(c.a), alphabet.Letters(c.b),
+		)
+
+		if assert.NoError(t, err) {
+			assert.InDelta(t, c.id, aln.Identity(), 1e-9,
+				"Identity of %q and %q should exclude terminal gaps.", c.a, c.b,
+			)
+		}
+	}
+
+	_, err := pairwise.Align(nw, alphabet.Letters("ACGU"), alphabet.Letters("ACGT"))
+
+	assert.Error(t, err, "Letters out of the alphabet should return an error.")
+}
+
+func TestAlign(t *testing.T) {
+	aln, err := pairwise.Align(
+		nw, alphabet.Letters(seqA), alphabet.Letters(seqA[9:20]+seqA[23:]),
+	)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 31, aln.Columns, "Terminal gaps should be excluded.")
+	assert.Equal(t, 28, aln.Matches, "Identical columns should be counted.")
+	assert.Equal(t, 0, aln.Mismatches, "Different columns should be counted.")
+	assert.Equal(t, 3, aln.Gaps, "Gap columns should be counted.")
+	assert.Equal(t, 1, aln.GapOpens, "Runs of gaps should be counted.")
+	assert.Equal(t,
+		[4]int{10, 40, 1, 28},
+		[4]int{aln.TargetStart, aln.TargetEnd, aln.QueryStart, aln.QueryEnd},
+		"Positions should be the first and last letters aligned.",
+	)
+	assert.Equal(t, "9D11M3D17M", aln.Compressed(),
+		"Alignment should be compressed with its terminal gaps.",
+	)
+	assert.Equal(t, 12, aln.Distance(),
+		"Distance should count the gaps with the terminal gaps.",
+	)
+
+	aln, err = pairwise.Align(nw, alphabet.Letters(seqA), alphabet.Letters(seqA))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "=", aln.Compressed(),
+			"Alignment of identical sequences should be compressed as =.",
+		)
+	}
+}
+
+func TestAlignOver(t *testing.T) {
+	const protA = "MKVLWAALLVTFLAGCQA"
+
+	// protB has 1 substitution to protA in 18 columns.
+	const protB = "MKVLWAALLVSFLAGCQA"
+
+	_, err := pairwise.Align(nw, alphabet.Letters(protA), alphabet.Letters(protB))
+
+	assert.Error(t, err, "Proteins should not be aligned over Alphabet.")
+
+	aln, err := pairwise.AlignOver(
+		pairwise.NewAlignerOf(feat.Protein), pairwise.AlphabetOf(feat.Protein),
+		alphabet.Letters(protA), alphabet.Letters(protB),
+	)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, 17, aln.Matches, "Proteins should be aligned.")
+		assert.Equal(t, 1, aln.Mismatches, "Substitutions should be counted.")
+	}
+
+	aln, err = pairwise.AlignOver(
+		pairwise.NewAlignerOf(feat.RNA), pairwise.AlphabetOf(feat.RNA),
+		alphabet.Letters("ACGUACGU"), alphabet.Letters("ACGUACGU"),
+	)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "=", aln.Compressed(),
+			"RNA should be aligned by the matrix of DNA.",
+		)
+	}
+}
+
+func TestRevComp(t *testing.T) {
+	assert.Equal(t, alphabet.Letters("NRacgT"),
+		pairwise.RevComp(alphabet.Letters("AcgtYN")),
+		"Letters should be reverse complemented with their cases.",
+	)
+}
+
+func TestIdentical(t *testing.T) {
+	aln, err := pairwise.Align(nw, alphabet.Letters(seqA), alphabet.Letters(seqA))
+
+	if assert.NoError(t, err) {
+		aln.Score = 0
+
+		assert.Equal(t, aln, pairwise.Identical(alphabet.Letters(seqA)),
+			"Alignment of identical letters should be the same as aligned.",
+		)
+	}
+}
+
+func TestCIGAR(t *testing.T) {
+	aln, err := pairwise.Align(
+		nw, alphabet.Letters(seqA), alphabet.Letters(seqA[9:20]+seqA[23:]),
+	)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "11M3D17M", aln.CIGAR(),
+			"CIGAR should exclude the terminal gaps.",
+		)
+		assert.InDelta(t, 1, aln.QueryCoverage(), 1e-9,
+			"Query should be covered in full.",
+		)
+		assert.InDelta(t, 0.775, aln.TargetCoverage(), 1e-9,
+			"Target should be covered without the terminal gaps.",
+		)
+		assert.Equal(t, 28*pairwise.Match+2*pairwise.GapOpen+12*pairwise.Gap, aln.Score,
+			"Score should include the terminal gaps of the global alignment.",
+		)
+	}
+
+	sw, err := pairwise.ModeAligner(pairwise.Local, nw.Matrix, 0, false)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	aln, err = pairwise.Align(
+		sw,
+		alphabet.Letters("TTTTT"+seqA[:20]+"TTTTT"),
+		alphabet.Letters("CCCCC"+seqA[:20]+"CCCCC"),
+	)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "5S20M5S", aln.CIGAR(),
+			"Letters of the query out of the alignment should be clipped.",
+		)
+	}
+
+	assert.Equal(t, "*", (&pairwise.Alignment{}).CIGAR(),
+		"CIGAR of an empty alignment should be *.",
+	)
+}
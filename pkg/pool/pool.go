@@ -0,0 +1,145 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package pool provides a pool of workers processing sequences in parallel,
+// whose results are collected in the order of the input.
+package pool
+
+import (
+	"context"
+	"sync"
+
+	"github.com/biogo/biogo/seq"
+	"golang.org/x/sync/errgroup"
+)
+
+// Buffer is the size of the channels between the workers.
+const Buffer = 64
+
+// job is a sequence with its index in the input and its result.
+type job struct {
+	i int64
+	s seq.Sequence
+	v interface{}
+}
+
+// Ordered calls work on the sequences from a channel on n workers and calls
+// out on their results in the order of the input, no matter which worker
+// finishes first. Ordered returns the first error of work or out.
+//
+// At most n*Buffer sequences are in flight, from dispatched to passed to out,
+// so a slow sequence holds back the input instead of piling up the results
+// finished after it.
+func Ordered(
+	ctx context.Context, n int, in <-chan seq.Sequence,
+	work func(seq.Sequence) (interface{}, error), out func(interface{}) error,
+) error {
+	if n < 1 {
+		n = 1
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+
+	jobs := make(chan job, Buffer)
+	done := make(chan job, Buffer)
+
+	// sem holds a token of each sequence in flight.
+	sem := make(chan struct{}, n*Buffer)
+
+	g.Go(func() error {
+		defer close(jobs)
+
+		for i := int64(0); ; i++ {
+			select {
+			case s, ok := <-in:
+				if !ok {
+					return nil
+				}
+
+				select {
+				case sem <- struct{}{}:
+				case <-gctx.Done():
+					return gctx.Err()
+				}
+
+				select {
+				case jobs <- job{i: i, s: s}:
+				case <-gctx.Done():
+					return gctx.Err()
+				}
+			case <-gctx.Done():
+				return gctx.Err()
+			}
+		}
+	})
+
+	var wg sync.WaitGroup
+
+	for k := 0; k < n; k++ {
+		wg.Add(1)
+
+		g.Go(func() error {
+			defer wg.Done()
+
+			for j := range jobs {
+				v, err := work(j.s)
+
+				if err != nil {
+					return err
+				}
+
+				j.v = v
+
+				select {
+				case done <- j:
+				case <-gctx.Done():
+					return gctx.Err()
+				}
+			}
+
+			return nil
+		})
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	g.Go(func() error {
+		// pending holds the results finished before the previous ones.
+		pending := make(map[int64]interface{})
+		next := int64(0)
+
+		for j := range done {
+			pending[j.i] = j.v
+
+			for v, ok := pending[next]; ok; v, ok = pending[next] {
+				delete(pending, next)
+				next++
+				<-sem
+
+				if err := out(v); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+
+	return g.Wait()
+}
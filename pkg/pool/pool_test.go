@@ -0,0 +1,156 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/pool"
+)
+
+// numbered returns a closed channel of n sequences named by their indices.
+func numbered(n int) <-chan seq.Sequence {
+	c := make(chan seq.Sequence, n)
+
+	for i := 0; i < n; i++ {
+		c <- linear.NewSeq(fmt.Sprint(i), []alphabet.Letter("ACGT"), alphabet.DNA)
+	}
+
+	close(c)
+
+	return c
+}
+
+func TestOrdered(t *testing.T) {
+	for _, n := range []int{0, 1, 4} {
+		var res []string
+
+		err := pool.Ordered(
+			context.Background(), n, numbered(50),
+			func(s seq.Sequence) (interface{}, error) {
+				id := s.(*linear.Seq).ID
+
+				// The earlier sequences finish later.
+				if len(id) == 1 {
+					time.Sleep(time.Millisecond)
+				}
+
+				return id, nil
+			},
+			func(v interface{}) error {
+				res = append(res, v.(string))
+				return nil
+			},
+		)
+
+		if assert.NoError(t, err) && assert.Len(t, res, 50) {
+			for i, id := range res {
+				if !assert.Equal(t, fmt.Sprint(i), id,
+					"Results of %d workers should be in the order of the input.", n,
+				) {
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestOrderedInFlight(t *testing.T) {
+	const n = 2
+
+	release := make(chan struct{})
+
+	// started counts the sequences started after the first one, and ahead is
+	// the count when the first one finishes.
+	var started, ahead int32
+
+	go func() {
+		// Waits for the workers to run ahead of the first sequence.
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+	}()
+
+	err := pool.Ordered(
+		context.Background(), n, numbered(10*n*pool.Buffer),
+		func(s seq.Sequence) (interface{}, error) {
+			if s.(*linear.Seq).ID == "0" {
+				<-release
+				ahead = atomic.LoadInt32(&started)
+			} else {
+				atomic.AddInt32(&started, 1)
+			}
+
+			return s, nil
+		},
+		func(interface{}) error { return nil },
+	)
+
+	if assert.NoError(t, err) {
+		assert.Less(t, int(ahead), n*pool.Buffer,
+			"A slow sequence should hold back the input.",
+		)
+	}
+}
+
+func TestOrderedError(t *testing.T) {
+	errWork := errors.New("work")
+
+	err := pool.Ordered(
+		context.Background(), 4, numbered(50),
+		func(seq.Sequence) (interface{}, error) { return nil, errWork },
+		func(interface{}) error { return nil },
+	)
+
+	assert.Equal(t, errWork, err, "The error of work should be returned.")
+
+	errOut := errors.New("out")
+
+	err = pool.Ordered(
+		context.Background(), 4, numbered(50),
+		func(s seq.Sequence) (interface{}, error) { return s, nil },
+		func(interface{}) error { return errOut },
+	)
+
+	assert.Equal(t, errOut, err, "The error of out should be returned.")
+}
+
+func TestOrderedCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cancel()
+
+	err := pool.Ordered(
+		ctx, 4, make(chan seq.Sequence),
+		func(s seq.Sequence) (interface{}, error) { return s, nil },
+		func(interface{}) error { return nil },
+	)
+
+	assert.Equal(t, context.Canceled, err,
+		"The error of the context should be returned.",
+	)
+}
@@ -0,0 +1,160 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package relabel provides the relabeling of sequences by a counter or by the
+// digest of their letters.
+package relabel
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"strconv"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/seq"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+	"github.com/mys721tx/gsearch/pkg/seqio"
+)
+
+// OrigKey is the key of the attribute keeping the original label.
+const OrigKey = "label"
+
+// Options configures a Relabeler.
+type Options struct {
+	// Prefix labels the sequences by the prefix followed by a counter from 1,
+	// as the --relabel option of vsearch.
+	Prefix string
+	// SHA1 labels the sequences by the SHA1 digest of their letters, as the
+	// --relabel_sha1 option of vsearch.
+	SHA1 bool
+	// MD5 labels the sequences by the MD5 digest of their letters, as the
+	// --relabel_md5 option of vsearch.
+	MD5 bool
+	// Keep keeps the original label in the attribute OrigKey.
+	Keep bool
+}
+
+var errMethods = errors.New("relabel: only one of prefix, SHA1 and MD5 can be set")
+
+// Relabeler relabels sequences. A Relabeler is not safe for concurrent use, as
+// the counter follows the order of the sequences relabeled.
+//
+// A nil Relabeler leaves the sequences unchanged.
+type Relabeler struct {
+	opt Options
+	n   int
+}
+
+// New returns a Relabeler of the options, or nil if none of Prefix, SHA1 and
+// MD5 is set.
+func New(opt Options) (*Relabeler, error) {
+	n := 0
+
+	for _, set := range []bool{opt.Prefix != "", opt.SHA1, opt.MD5} {
+		if set {
+			n++
+		}
+	}
+
+	switch n {
+	case 0:
+		return nil, nil
+	case 1:
+		return &Relabeler{opt: opt}, nil
+	}
+
+	return nil, errMethods
+}
+
+// Label returns the next label of a sequence.
+func (r *Relabeler) Label(s seq.Sequence) string {
+	switch {
+	case r.opt.SHA1:
+		return Digest(sha1.New(), s)
+	case r.opt.MD5:
+		return Digest(md5.New(), s)
+	}
+
+	r.n++
+
+	return r.opt.Prefix + strconv.Itoa(r.n)
+}
+
+// Cluster relabels a cluster. With Keep, the original ID is kept in the
+// attribute OrigKey.
+func (r *Relabeler) Cluster(c *cluster.Cluster) {
+	if r == nil {
+		return
+	}
+
+	if r.opt.Keep {
+		c.Attrs = append(c.Attrs, cluster.Attr{Key: OrigKey, Value: c.ID})
+	}
+
+	c.ID = r.Label(c)
+}
+
+// Seq relabels a sequence. With Keep, the original name is appended as the
+// attribute OrigKey.
+func (r *Relabeler) Seq(s *linear.Seq) {
+	if r == nil {
+		return
+	}
+
+	label := r.Label(s)
+
+	if r.opt.Keep {
+		label += ";" + OrigKey + "=" + s.ID
+	}
+
+	s.ID = label
+}
+
+// Digest returns the hexadecimal digest of the letters of a sequence. The
+// letters are in upper case and U of a nucleotide sequence is read as T, as
+// vsearch. The U of a protein sequence is selenocysteine and is kept.
+func Digest(h hash.Hash, s seq.Sequence) string {
+	l := seqio.AsSeq(s)
+	b := make([]byte, len(l.Seq))
+
+	nuc := false
+
+	if a := s.Alphabet(); a != nil {
+		nuc = a.Moltype() == feat.DNA || a.Moltype() == feat.RNA
+	}
+
+	for i, v := range alphabet.LettersToBytes(l.Seq) {
+		if v >= 'a' && v <= 'z' {
+			v -= 'a' - 'A'
+		}
+
+		if nuc && v == 'U' {
+			v = 'T'
+		}
+
+		b[i] = v
+	}
+
+	h.Write(b)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,152 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package relabel_test
+
+import (
+	"crypto/sha1"
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+	"github.com/mys721tx/gsearch/pkg/relabel"
+)
+
+const (
+	sha1ACGT = "2108994e17f6cca9ff2352ada92b6511db076034"
+	md5ACGT  = "f1f8f4bf413b16ad135722aa4591043e"
+)
+
+func TestNew(t *testing.T) {
+	r, err := relabel.New(relabel.Options{Keep: true})
+
+	assert.NoError(t, err)
+	assert.Nil(t, r, "No method should return a nil Relabeler.")
+
+	_, err = relabel.New(relabel.Options{Prefix: "Uniq", SHA1: true})
+
+	assert.Error(t, err, "More than one method should return an error.")
+
+	_, err = relabel.New(relabel.Options{SHA1: true, MD5: true})
+
+	assert.Error(t, err, "More than one method should return an error.")
+}
+
+func TestCounter(t *testing.T) {
+	r, err := relabel.New(relabel.Options{Prefix: "Uniq"})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, exp := range []string{"Uniq1", "Uniq2", "Uniq3"} {
+		c := cluster.ParseAnno(
+			linear.NewSeq("foo;size=2", []alphabet.Letter("ACGT"), alphabet.DNA),
+		)
+
+		r.Cluster(c)
+
+		assert.Equal(t, exp+";size=2", c.Name(),
+			"Clusters should be labeled by the prefix and a counter.",
+		)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	for opt, exp := range map[relabel.Options]string{
+		{SHA1: true}: sha1ACGT,
+		{MD5: true}:  md5ACGT,
+	} {
+		r, err := relabel.New(opt)
+
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		for _, l := range []string{"ACGT", "acgt", "ACGU"} {
+			c := cluster.ParseAnno(
+				linear.NewSeq("foo", []alphabet.Letter(l), alphabet.DNA),
+			)
+
+			r.Cluster(c)
+
+			assert.Equal(t, exp, c.ID,
+				"Clusters should be labeled by the digest of %q.", l,
+			)
+		}
+	}
+}
+
+func TestDigestProtein(t *testing.T) {
+	digest := func(l string) string {
+		return relabel.Digest(
+			sha1.New(),
+			linear.NewSeq("foo", []alphabet.Letter(l), alphabet.Protein),
+		)
+	}
+
+	assert.NotEqual(t, digest("MKTA"), digest("MKUA"),
+		"Selenocysteine should not be read as threonine.",
+	)
+	assert.Equal(t, digest("MKUA"), digest("mkua"),
+		"Protein letters should be in upper case.",
+	)
+}
+
+func TestKeep(t *testing.T) {
+	r, err := relabel.New(relabel.Options{SHA1: true, Keep: true})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	c := cluster.ParseAnno(linear.NewSeq(
+		"foo;sample=A;size=3", []alphabet.Letter("ACGT"), alphabet.DNA,
+	))
+
+	r.Cluster(c)
+
+	assert.Equal(t, sha1ACGT+";sample=A;label=foo;size=3", c.Name(),
+		"The original label should be kept as an attribute.",
+	)
+
+	s := linear.NewSeq("bar", []alphabet.Letter("ACGT"), alphabet.DNA)
+
+	r.Seq(s)
+
+	assert.Equal(t, sha1ACGT+";label=bar", s.ID,
+		"The original label should be appended as an attribute.",
+	)
+}
+
+func TestNil(t *testing.T) {
+	var r *relabel.Relabeler
+
+	c := cluster.ParseAnno(
+		linear.NewSeq("foo", []alphabet.Letter("ACGT"), alphabet.DNA),
+	)
+	s := linear.NewSeq("bar", []alphabet.Letter("ACGT"), alphabet.DNA)
+
+	r.Cluster(c)
+	r.Seq(s)
+
+	assert.Equal(t, "foo", c.ID, "A nil Relabeler should not relabel.")
+	assert.Equal(t, "bar", s.ID, "A nil Relabeler should not relabel.")
+}
@@ -0,0 +1,79 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package search
+
+import (
+	"bytes"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/mys721tx/gsearch/pkg/derep"
+	"github.com/mys721tx/gsearch/pkg/pairwise"
+)
+
+// exactKey returns the dereplication key of a sequence in upper case, so the
+// keys are case insensitive as the words of kmer.
+func exactKey(s *linear.Seq, strand derep.Strand) string {
+	u := *s
+	u.Seq = alphabet.BytesToLetters(bytes.ToUpper(alphabet.LettersToBytes(s.Seq)))
+
+	return derep.Key(&u, strand)
+}
+
+// exactIndex maps the dereplication keys of the targets to their numbers, as
+// the --search_exact command of vsearch.
+type exactIndex map[string][]int
+
+// newExactIndex returns the exactIndex of the targets of a DB on a strand.
+func newExactIndex(db *DB, strand derep.Strand) exactIndex {
+	ix := make(exactIndex, len(db.seqs))
+
+	for i, t := range db.seqs {
+		k := exactKey(t, strand)
+		ix[k] = append(ix[k], i)
+	}
+
+	return ix
+}
+
+// search returns the targets identical to a query over their full lengths,
+// in the order of the DB. With derep.Both, the targets identical to the
+// reverse complement of the query are hits on the minus strand.
+func (ix exactIndex) search(db *DB, q *linear.Seq, strand derep.Strand) []Hit {
+	ids := ix[exactKey(q, strand)]
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	hits := make([]Hit, len(ids))
+	plus := alphabet.LettersToBytes(q.Seq)
+
+	for i, id := range ids {
+		t := db.seqs[id]
+
+		hits[i] = Hit{Target: id, Strand: seq.Plus, Alignment: pairwise.Identical(t.Seq)}
+
+		if !bytes.EqualFold(alphabet.LettersToBytes(t.Seq), plus) {
+			hits[i].Strand = seq.Minus
+		}
+	}
+
+	return hits
+}
@@ -0,0 +1,104 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package search_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/derep"
+	"github.com/mys721tx/gsearch/pkg/pairwise"
+	"github.com/mys721tx/gsearch/pkg/search"
+)
+
+func TestSearchExact(t *testing.T) {
+	db := newDB(t)
+
+	db.Add(newSeq("a2", seqA))
+
+	rc := string(pairwise.RevComp(alphabet.Letters(seqA)))
+	lower := strings.ToLower(seqA)
+
+	cases := []struct {
+		opt  search.Options
+		q    string
+		hits []string
+		msg  string
+	}{
+		{
+			search.Options{}, seqA, []string{"0+", "3+"},
+			"Identical targets should be hits in their order.",
+		},
+		{
+			search.Options{}, seqB, nil,
+			"Similar targets should not be hits.",
+		},
+		{
+			search.Options{}, seqA[1:], nil,
+			"Targets should be identical over their full lengths.",
+		},
+		{
+			search.Options{}, lower, []string{"0+", "3+"},
+			"Letters should be compared case insensitively.",
+		},
+		{
+			search.Options{Strand: derep.Both}, strings.ToLower(rc),
+			[]string{"0-", "3-"},
+			"Reverse complement should be compared case insensitively.",
+		},
+		{
+			search.Options{MaxHits: 1}, seqA, []string{"0+"},
+			"Hits should be limited to the maximal hits.",
+		},
+		{
+			search.Options{}, rc, nil,
+			"Reverse complement should not hit on the plus strand.",
+		},
+		{
+			search.Options{Strand: derep.Both}, rc, []string{"0-", "3-"},
+			"Reverse complement should hit on the minus strand.",
+		},
+		{
+			search.Options{Strand: derep.Both}, seqA, []string{"0+", "3+"},
+			"Query should hit on the plus strand.",
+		},
+	}
+
+	for _, c := range cases {
+		c.opt.Exact = true
+
+		s, err := search.New(db, c.opt)
+
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		hits, err := s.Search(newSeq("q", c.q))
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, c.hits, targets(hits), c.msg)
+
+			for _, h := range hits {
+				assert.Equal(t, 1.0, h.Identity(), "Hits should be identical.")
+			}
+		}
+	}
+}
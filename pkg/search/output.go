@@ -0,0 +1,267 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package search
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/biogo/biogo/seq"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/mys721tx/gsearch/pkg/uc"
+)
+
+// Field is a column of the tabular output, as the --userfields option of
+// vsearch.
+type Field int
+
+// The fields of the tabular output.
+const (
+	// Query is the label of the query.
+	Query Field = iota
+	// Target is the label of the target.
+	Target
+	// ID is the percent identity.
+	ID
+	// AlnLen is the number of columns of the alignment.
+	AlnLen
+	// Mism is the number of mismatches.
+	Mism
+	// Opens is the number of gap opens.
+	Opens
+	// Gaps is the number of gap columns.
+	Gaps
+	// IDs is the number of identical columns.
+	IDs
+	// QLo and QHi are the first and last positions of the query aligned. On
+	// the minus strand, QLo is greater than QHi.
+	QLo
+	QHi
+	// TLo and THi are the first and last positions of the target aligned.
+	TLo
+	THi
+	// QL and TL are the lengths of the query and the target.
+	QL
+	TL
+	// QStrand is the strand of the query.
+	QStrand
+	// QCov and TCov are the percent of the query and the target aligned.
+	QCov
+	TCov
+	// CAln is the compressed alignment.
+	CAln
+	// EValue is always -1, as vsearch.
+	EValue
+	// Bits is always 0, as vsearch.
+	Bits
+)
+
+var fieldNames = [...]string{
+	"query", "target", "id", "alnlen", "mism", "opens", "gaps", "ids",
+	"qlo", "qhi", "tlo", "thi", "ql", "tl", "qstrand", "qcov", "tcov",
+	"caln", "evalue", "bits",
+}
+
+// String returns the name of the field.
+func (f Field) String() string {
+	if f < 0 || int(f) >= len(fieldNames) {
+		return fmt.Sprintf("Field(%d)", int(f))
+	}
+	return fieldNames[f]
+}
+
+// Blast6 is the fields of the BLAST tabular output, as the --blast6out option
+// of vsearch.
+var Blast6 = []Field{
+	Query, Target, ID, AlnLen, Mism, Opens, QLo, QHi, TLo, THi, EValue, Bits,
+}
+
+// ParseFields parses the names of fields separated by "+", such as
+// "query+target+id".
+func ParseFields(s string) ([]Field, error) {
+	var res []Field
+
+	for _, name := range strings.Split(s, "+") {
+		f := Field(-1)
+
+		for i, v := range fieldNames {
+			if v == name {
+				f = Field(i)
+				break
+			}
+		}
+
+		if f < 0 {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+
+		res = append(res, f)
+	}
+
+	return res, nil
+}
+
+// Writer writes the hits as lines of tab-separated fields.
+type Writer struct {
+	w      io.Writer
+	db     *DB
+	fields []Field
+}
+
+// NewWriter returns a Writer of the fields of the hits against a DB.
+func NewWriter(w io.Writer, db *DB, fields []Field) *Writer {
+	return &Writer{w: w, db: db, fields: fields}
+}
+
+// Write writes a line of each hit of a result.
+func (w *Writer) Write(r *Result) error {
+	for i := range r.Hits {
+		if err := w.write(r.Query, &r.Hits[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteNoHit writes a line of a query without hit, as the --output_no_hits
+// option of vsearch: the target is "*" and the other fields of the hit are
+// 0.
+func (w *Writer) WriteNoHit(q *linear.Seq) error {
+	return w.write(q, nil)
+}
+
+// write writes a line of a hit, or of a query without hit if h is nil.
+func (w *Writer) write(q *linear.Seq, h *Hit) error {
+	var b strings.Builder
+
+	for i, f := range w.fields {
+		if i > 0 {
+			b.WriteByte('\t')
+		}
+
+		b.WriteString(w.field(f, q, h))
+	}
+
+	b.WriteByte('\n')
+
+	_, err := io.WriteString(w.w, b.String())
+
+	return err
+}
+
+// field formats a field of a hit.
+func (w *Writer) field(f Field, q *linear.Seq, h *Hit) string {
+	switch f {
+	case Query:
+		return q.ID
+	case QL:
+		return fmt.Sprint(q.Len())
+	case EValue:
+		return "-1"
+	case Bits:
+		return "0"
+	}
+
+	if h == nil {
+		switch f {
+		case Target, QStrand, CAln:
+			return "*"
+		case ID, QCov, TCov:
+			return "0.0"
+		}
+		return "0"
+	}
+
+	t := w.db.Seq(h.Target)
+
+	switch f {
+	case Target:
+		return t.ID
+	case ID:
+		return fmt.Sprintf("%.1f", h.Identity()*100)
+	case AlnLen:
+		return fmt.Sprint(h.Columns)
+	case Mism:
+		return fmt.Sprint(h.Mismatches)
+	case Opens:
+		return fmt.Sprint(h.GapOpens)
+	case Gaps:
+		return fmt.Sprint(h.Alignment.Gaps)
+	case IDs:
+		return fmt.Sprint(h.Matches)
+	case QLo, QHi:
+		lo, hi := h.QueryStart, h.QueryEnd
+
+		if h.Strand == seq.Minus {
+			lo, hi = q.Len()-lo+1, q.Len()-hi+1
+		}
+
+		if f == QLo {
+			return fmt.Sprint(lo)
+		}
+		return fmt.Sprint(hi)
+	case TLo:
+		return fmt.Sprint(h.TargetStart)
+	case THi:
+		return fmt.Sprint(h.TargetEnd)
+	case TL:
+		return fmt.Sprint(t.Len())
+	case QStrand:
+		if h.Strand == seq.Minus {
+			return "-"
+		}
+		return "+"
+	case QCov:
+		return coverage(h.QueryEnd-h.QueryStart+1, q.Len())
+	case TCov:
+		return coverage(h.TargetEnd-h.TargetStart+1, t.Len())
+	case CAln:
+		return h.Compressed()
+	}
+
+	return ""
+}
+
+// coverage formats the percent of n letters over a length.
+func coverage(n, length int) string {
+	if length == 0 {
+		return "0.0"
+	}
+	return fmt.Sprintf("%.1f", float64(n)*100/float64(length))
+}
+
+// UC returns the UC records of the hits of a result.
+func (r *Result) UC(db *DB) []uc.Hit {
+	res := make([]uc.Hit, len(r.Hits))
+
+	for i, h := range r.Hits {
+		res[i] = uc.Hit{
+			Cluster:   h.Target,
+			Len:       r.Query.Len(),
+			Identity:  h.Identity() * 100,
+			Strand:    h.Strand,
+			Alignment: h.Compressed(),
+			Query:     r.Query.ID,
+			Target:    db.Seq(h.Target).ID,
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,129 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package search_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/derep"
+	"github.com/mys721tx/gsearch/pkg/pairwise"
+	"github.com/mys721tx/gsearch/pkg/search"
+	"github.com/mys721tx/gsearch/pkg/uc"
+)
+
+func TestParseFields(t *testing.T) {
+	f, err := search.ParseFields("query+target+id+qcov")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t,
+			[]search.Field{search.Query, search.Target, search.ID, search.QCov},
+			f, "Fields should be parsed in their order.",
+		)
+	}
+
+	for _, s := range []string{"", "query+", "query+score"} {
+		_, err := search.ParseFields(s)
+
+		assert.Error(t, err, "Unknown field %q should return an error.", s)
+	}
+
+	assert.Equal(t, "qstrand", search.QStrand.String(),
+		"Name should be the same.",
+	)
+}
+
+// searchOne searches a query against the DB of newDB on both strands.
+func searchOne(t *testing.T, db *search.DB, name, letters string) *search.Result {
+	s, err := search.New(db, search.Options{Identity: 0.97, Strand: derep.Both})
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	r := &search.Result{Query: newSeq(name, letters)}
+
+	r.Hits, err = s.Search(r.Query)
+
+	assert.NoError(t, err)
+
+	return r
+}
+
+func TestWriter(t *testing.T) {
+	db := newDB(t)
+
+	rc := string(pairwise.RevComp(alphabet.Letters(seqB[5:])))
+
+	b := new(bytes.Buffer)
+	w := search.NewWriter(b, db, search.Blast6)
+
+	assert.NoError(t, w.Write(searchOne(t, db, "q1", seqB)))
+	assert.NoError(t, w.Write(searchOne(t, db, "q2", rc)))
+	assert.NoError(t, w.Write(searchOne(t, db, "q3", "GGGGGGGGGGGGGGGGGGGG")))
+	assert.NoError(t, w.WriteNoHit(newSeq("q4", "ACGT")))
+
+	assert.Equal(t,
+		"q1\ta\t97.5\t40\t1\t0\t1\t40\t1\t40\t-1\t0\n"+
+			"q2\ta\t97.1\t35\t1\t0\t35\t1\t6\t40\t-1\t0\n"+
+			"q4\t*\t0.0\t0\t0\t0\t0\t0\t0\t0\t-1\t0\n",
+		b.String(),
+		"Hits should be written as BLAST tabular lines.",
+	)
+
+	f, err := search.ParseFields("query+target+qstrand+ql+tl+qcov+tcov+ids+gaps+caln")
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	b.Reset()
+	w = search.NewWriter(b, db, f)
+
+	assert.NoError(t, w.Write(searchOne(t, db, "q2", rc)))
+	assert.NoError(t, w.WriteNoHit(newSeq("q4", "ACGT")))
+
+	assert.Equal(t,
+		"q2\ta\t-\t35\t40\t100.0\t87.5\t34\t0\t5D35M\n"+
+			"q4\t*\t*\t4\t0\t0.0\t0.0\t0\t0\t*\n",
+		b.String(),
+		"Hits should be written as the fields.",
+	)
+}
+
+func TestResultUC(t *testing.T) {
+	db := newDB(t)
+
+	assert.Equal(t,
+		[]uc.Hit{{
+			Cluster:   0,
+			Len:       40,
+			Identity:  97.5,
+			Strand:    seq.Plus,
+			Alignment: "40M",
+			Query:     "q1",
+			Target:    "a",
+		}},
+		searchOne(t, db, "q1", seqB).UC(db),
+		"Hits should be converted to UC records.",
+	)
+}
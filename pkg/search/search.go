@@ -0,0 +1,297 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package search provides the global search of query sequences against a
+// database of target sequences, as the --usearch_global command of vsearch.
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/biogo/biogo/align"
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/seq"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/mys721tx/gsearch/pkg/derep"
+	"github.com/mys721tx/gsearch/pkg/kmer"
+	"github.com/mys721tx/gsearch/pkg/pairwise"
+	"github.com/mys721tx/gsearch/pkg/pool"
+	"github.com/mys721tx/gsearch/pkg/seqio"
+)
+
+// DefaultIdentity is the default minimal identity of a hit.
+const DefaultIdentity = 0.97
+
+// DB is a database of target sequences indexed by their words.
+type DB struct {
+	seqs  []*linear.Seq
+	index *kmer.Index
+	mol   feat.Moltype
+}
+
+// NewDB returns an empty DB of nucleotides indexed by the words of length k.
+func NewDB(k int) (*DB, error) {
+	return NewDBOf(k, feat.DNA)
+}
+
+// NewDBOf returns an empty DB of a molecule indexed by the words of length k,
+// as kmer.NewIndexOf.
+func NewDBOf(k int, mol feat.Moltype) (*DB, error) {
+	ix, err := kmer.NewIndexOf(k, mol)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DB{index: ix, mol: mol}, nil
+}
+
+// Moltype returns the molecule of the targets.
+func (db *DB) Moltype() feat.Moltype {
+	return db.mol
+}
+
+// Add adds a target to the DB and returns its number, from 0.
+func (db *DB) Add(s *linear.Seq) int {
+	db.seqs = append(db.seqs, s)
+	return db.index.Insert(s.Seq)
+}
+
+// Len returns the number of targets in the DB.
+func (db *DB) Len() int {
+	return len(db.seqs)
+}
+
+// Seq returns the target of number i.
+func (db *DB) Seq(i int) *linear.Seq {
+	return db.seqs[i]
+}
+
+// Options configures a Searcher.
+type Options struct {
+	// Identity is the minimal identity of a hit, from 0 to 1.
+	Identity float64
+	// Aligner aligns the queries to the targets, or nil for
+	// pairwise.NewAlignerOf the molecule of the DB. The sequences are aligned
+	// over pairwise.AlphabetOf the molecule.
+	Aligner align.Aligner
+	// MaxAccepts and MaxRejects limit the targets aligned to a query, as
+	// kmer.Index.Search. With derep.Both, the limits are shared by the two
+	// strands, as vsearch. A limit of 0 is unlimited.
+	MaxAccepts, MaxRejects int
+	// MaxHits is the maximal number of hits of a query, or 0 for all the
+	// accepted targets.
+	MaxHits int
+	// Strand is the strand of the queries searched. With derep.Both, the
+	// reverse complement of a query is also searched, which needs a DB of
+	// nucleotides.
+	Strand derep.Strand
+	// Threads is the number of queries searched in parallel by Run.
+	Threads int
+	// Exact searches the targets identical to the queries over their full
+	// lengths by a hash of their dereplication keys, without alignment.
+	// Identity, Aligner, MaxAccepts and MaxRejects are ignored.
+	Exact bool
+}
+
+var (
+	errIdentity = errors.New("search: identity should be between 0 and 1")
+	errStrand   = errors.New("search: proteins cannot be searched on both strands")
+)
+
+// Hit is the alignment of a query to a target.
+type Hit struct {
+	// Target is the number of the target in the DB.
+	Target int
+	// Strand is the strand of the query aligned to the target.
+	Strand seq.Strand
+	*pairwise.Alignment
+}
+
+// Result is the hits of a query, from the highest identity.
+type Result struct {
+	Query *linear.Seq
+	Hits  []Hit
+}
+
+// Searcher searches queries against a DB.
+type Searcher struct {
+	db    *DB
+	opt   Options
+	alpha alphabet.Alphabet
+	exact exactIndex
+}
+
+// New returns a Searcher of a DB. With Options.Exact, the targets added to the
+// DB after New are not searched.
+func New(db *DB, opt Options) (*Searcher, error) {
+	if opt.Identity < 0 || opt.Identity > 1 {
+		return nil, errIdentity
+	}
+
+	if opt.Strand == derep.Both && db.mol == feat.Protein {
+		return nil, errStrand
+	}
+
+	if opt.Aligner == nil {
+		opt.Aligner = pairwise.NewAlignerOf(db.mol)
+	}
+
+	if opt.Threads < 1 {
+		opt.Threads = 1
+	}
+
+	s := &Searcher{db: db, opt: opt, alpha: pairwise.AlphabetOf(db.mol)}
+
+	if opt.Exact {
+		s.exact = newExactIndex(db, opt.Strand)
+	}
+
+	return s, nil
+}
+
+// Search returns the hits of a query: the candidate targets within
+// Options.MaxAccepts and Options.MaxRejects whose identity is at least
+// Options.Identity. With derep.Both, the candidates of the two strands are
+// ranked together by their shared words. The hits are sorted by decreasing
+// identity, the first compared among ties, and at most Options.MaxHits are
+// kept.
+//
+// With Options.Exact, the hits are the targets identical to the query, in the
+// order of the DB.
+func (s *Searcher) Search(q *linear.Seq) ([]Hit, error) {
+	if s.exact != nil {
+		hits := s.exact.search(s.db, q, s.opt.Strand)
+
+		if s.opt.MaxHits > 0 && len(hits) > s.opt.MaxHits {
+			hits = hits[:s.opt.MaxHits]
+		}
+
+		return hits, nil
+	}
+
+	hits, err := s.search(s.candidates(q))
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Identity() > hits[j].Identity()
+	})
+
+	if s.opt.MaxHits > 0 && len(hits) > s.opt.MaxHits {
+		hits = hits[:s.opt.MaxHits]
+	}
+
+	return hits, nil
+}
+
+// candidate is a target sharing words with a query on a strand.
+type candidate struct {
+	kmer.Candidate
+	// q is the query on the strand.
+	q      *linear.Seq
+	strand seq.Strand
+}
+
+// candidates returns the targets sharing words with a query on the strands of
+// Options.Strand, ranked by their shared words. Among ties, the plus strand
+// comes first, then the lower number.
+func (s *Searcher) candidates(q *linear.Seq) []candidate {
+	var res []candidate
+
+	for _, c := range s.db.index.Candidates(q.Seq) {
+		res = append(res, candidate{Candidate: c, q: q, strand: seq.Plus})
+	}
+
+	if s.opt.Strand != derep.Both {
+		return res
+	}
+
+	rc := *q
+	rc.Seq = pairwise.RevComp(q.Seq)
+
+	for _, c := range s.db.index.Candidates(rc.Seq) {
+		res = append(res, candidate{Candidate: c, q: &rc, strand: seq.Minus})
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Shared > res[j].Shared
+	})
+
+	return res
+}
+
+// search aligns the candidates in their rank and returns the accepted
+// targets, until Options.MaxAccepts targets are accepted or
+// Options.MaxRejects targets are rejected.
+func (s *Searcher) search(cands []candidate) ([]Hit, error) {
+	var hits []Hit
+
+	rejects := 0
+
+	for _, c := range cands {
+		aln, err := pairwise.AlignOver(s.opt.Aligner, s.alpha, s.db.seqs[c.ID].Seq, c.q.Seq)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if aln.Identity() < s.opt.Identity {
+			rejects++
+		} else {
+			hits = append(hits, Hit{Target: c.ID, Strand: c.strand, Alignment: aln})
+		}
+
+		if (s.opt.MaxAccepts > 0 && len(hits) >= s.opt.MaxAccepts) ||
+			(s.opt.MaxRejects > 0 && rejects >= s.opt.MaxRejects) {
+			break
+		}
+	}
+
+	return hits, nil
+}
+
+// Run searches the queries from a channel on Options.Threads workers and calls
+// out on their results in the order of the input. Run returns the first error
+// of the search or out.
+func (s *Searcher) Run(ctx context.Context, in <-chan seq.Sequence, out func(*Result) error) error {
+	return pool.Ordered(
+		ctx, s.opt.Threads, in,
+		func(q seq.Sequence) (interface{}, error) {
+			r := &Result{Query: seqio.AsSeq(q)}
+
+			hits, err := s.Search(r.Query)
+
+			if err != nil {
+				return nil, fmt.Errorf("search: query %q: %w", r.Query.ID, err)
+			}
+
+			r.Hits = hits
+
+			return r, nil
+		},
+		func(v interface{}) error {
+			return out(v.(*Result))
+		},
+	)
+}
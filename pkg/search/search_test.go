@@ -0,0 +1,327 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package search_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/seq"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/derep"
+	"github.com/mys721tx/gsearch/pkg/kmer"
+	"github.com/mys721tx/gsearch/pkg/pairwise"
+	"github.com/mys721tx/gsearch/pkg/search"
+)
+
+const (
+	seqA = "ACGTTGCAAGGCTTACCGATGCATGCAAGTCCTAGGATCA"
+	// seqB has 1 mismatch to seqA in 40 columns.
+	seqB = "ACGTTGCAAGGCTTACCGATCCATGCAAGTCCTAGGATCA"
+	// seqC has 4 mismatches to seqA in 40 columns.
+	seqC = "ACGTAGCAAGGCTAACCGATGCATGCTAGTCCTAGCATCA"
+	// seqD shares no word with the others.
+	seqD = "TTTTTTTTTTCCCCCCCCCCTTTTTTTTTTCCCCCCCCCC"
+)
+
+func newSeq(name, letters string) *linear.Seq {
+	return linear.NewSeq(name, []alphabet.Letter(letters), alphabet.DNA)
+}
+
+// newDB returns a DB of seqA, seqC and seqD.
+func newDB(t *testing.T) *search.DB {
+	db, err := search.NewDB(kmer.DefaultK)
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	db.Add(newSeq("a", seqA))
+	db.Add(newSeq("c", seqC))
+	db.Add(newSeq("d", seqD))
+
+	return db
+}
+
+// targets returns the targets and strands of the hits.
+func targets(hits []search.Hit) []string {
+	var res []string
+
+	for _, h := range hits {
+		s := "+"
+
+		if h.Strand == seq.Minus {
+			s = "-"
+		}
+
+		res = append(res, fmt.Sprintf("%d%s", h.Target, s))
+	}
+
+	return res
+}
+
+func TestNewDB(t *testing.T) {
+	_, err := search.NewDB(0)
+
+	assert.Error(t, err, "Invalid word length should return an error.")
+}
+
+func TestNew(t *testing.T) {
+	for _, id := range []float64{-0.1, 1.1} {
+		_, err := search.New(newDB(t), search.Options{Identity: id})
+
+		assert.Error(t, err, "Identity out of range should return an error.")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	db := newDB(t)
+
+	assert.Equal(t, 3, db.Len(), "Targets should be counted.")
+
+	rc := string(pairwise.RevComp(alphabet.Letters(seqB)))
+
+	cases := []struct {
+		opt  search.Options
+		q    string
+		hits []string
+		msg  string
+	}{
+		{
+			search.Options{Identity: 0.97}, seqB, []string{"0+"},
+			"Targets above the identity should be hits.",
+		},
+		{
+			search.Options{Identity: 0.85}, seqB, []string{"0+", "1+"},
+			"Hits should be sorted by identity.",
+		},
+		{
+			search.Options{Identity: 0.85, MaxAccepts: 1}, seqB, []string{"0+"},
+			"Search should stop after the maximal accepts.",
+		},
+		{
+			search.Options{Identity: 0.85, MaxHits: 1}, seqB, []string{"0+"},
+			"Hits should be limited to the maximal hits.",
+		},
+		{
+			search.Options{Identity: 0.97}, rc, nil,
+			"Reverse complement should not hit on the plus strand.",
+		},
+		{
+			search.Options{Identity: 0.97, Strand: derep.Both}, rc, []string{"0-"},
+			"Reverse complement should hit on the minus strand.",
+		},
+	}
+
+	for _, c := range cases {
+		s, err := search.New(db, c.opt)
+
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		hits, err := s.Search(newSeq("q", c.q))
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, c.hits, targets(hits), c.msg)
+		}
+	}
+}
+
+func TestSearchBothStrands(t *testing.T) {
+	db, err := search.NewDB(kmer.DefaultK)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	db.Add(newSeq("a", seqA))
+	db.Add(newSeq("r", string(pairwise.RevComp(alphabet.Letters(seqA)))))
+
+	cases := []struct {
+		max  int
+		hits []string
+		msg  string
+	}{
+		{0, []string{"0+", "1-"}, "Query should hit on both strands."},
+		{1, []string{"0+"}, "The maximal accepts should be shared by the strands."},
+	}
+
+	for _, c := range cases {
+		s, err := search.New(db, search.Options{
+			Identity: 0.97, MaxAccepts: c.max, Strand: derep.Both,
+		})
+
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		hits, err := s.Search(newSeq("q", seqB))
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, c.hits, targets(hits), c.msg)
+		}
+	}
+}
+
+func TestSearchProtein(t *testing.T) {
+	const (
+		protA = "MKVLWAALLVTFLAGCQAKVEQAVETEPEPELRQQTEWQS"
+		// protB has 1 substitution to protA in 40 columns.
+		protB = "MKVLWAALLVTFLAGCQAKVEQAVETEPEPELRQQAEWQS"
+		protC = "MSTNPKPQRKTKRNTNRRPQDVKFPGGGQIVGGVYLLPRR"
+	)
+
+	db, err := search.NewDBOf(kmer.DefaultProteinK, feat.Protein)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	db.Add(linear.NewSeq("a", []alphabet.Letter(protA), alphabet.Protein))
+	db.Add(linear.NewSeq("c", []alphabet.Letter(protC), alphabet.Protein))
+
+	_, err = search.New(db, search.Options{Strand: derep.Both})
+
+	assert.Error(t, err, "Proteins should not be searched on both strands.")
+
+	s, err := search.New(db, search.Options{Identity: 0.97})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	hits, err := s.Search(
+		linear.NewSeq("q", []alphabet.Letter(protB), alphabet.Protein),
+	)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"0+"}, targets(hits),
+			"Proteins should hit the targets above the identity.",
+		)
+	}
+}
+
+func TestSearchIllegal(t *testing.T) {
+	s, err := search.New(newDB(t), search.Options{})
+
+	if assert.NoError(t, err) {
+		_, err := s.Search(newSeq("q", "ACGTTGCA*"))
+
+		assert.Error(t, err, "Letters out of the alphabet should return an error.")
+	}
+}
+
+func TestRun(t *testing.T) {
+	db := newDB(t)
+
+	var queries []seq.Sequence
+
+	for i := 0; i < 100; i++ {
+		l := seqB
+
+		if i%3 == 0 {
+			l = seqD[:20] + seqA[20:]
+		}
+
+		queries = append(queries, newSeq(fmt.Sprint(i), l))
+	}
+
+	for _, n := range []int{1, 4} {
+		s, err := search.New(db, search.Options{Identity: 0.97, Threads: n})
+
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		c := make(chan seq.Sequence, len(queries))
+
+		for _, q := range queries {
+			c <- q
+		}
+
+		close(c)
+
+		var ids []string
+		var hits int
+
+		err = s.Run(context.Background(), c, func(r *search.Result) error {
+			ids = append(ids, r.Query.ID)
+			hits += len(r.Hits)
+			return nil
+		})
+
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		if assert.Len(t, ids, len(queries)) {
+			for i, id := range ids {
+				if !assert.Equal(t, fmt.Sprint(i), id,
+					"Results of %d threads should be in the order of the input.", n,
+				) {
+					break
+				}
+			}
+		}
+
+		assert.Equal(t, 66, hits, "Each query of seqB should hit seqA.")
+	}
+}
+
+func TestRunError(t *testing.T) {
+	s, err := search.New(newDB(t), search.Options{Threads: 4})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	c := make(chan seq.Sequence, 10)
+
+	for i := 0; i < cap(c); i++ {
+		c <- newSeq(fmt.Sprint(i), seqB)
+	}
+
+	close(c)
+
+	errOut := errors.New("out")
+
+	err = s.Run(context.Background(), c, func(*search.Result) error {
+		return errOut
+	})
+
+	assert.Equal(t, errOut, err, "The error of out should be returned.")
+
+	c = make(chan seq.Sequence)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cancel()
+
+	err = s.Run(ctx, c, func(*search.Result) error { return nil })
+
+	assert.Equal(t, context.Canceled, err,
+		"The error of the context should be returned.",
+	)
+}
@@ -0,0 +1,104 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package seqio
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/dsnet/compress/bzip2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+)
+
+const (
+	// DefaultLevel is the level to use the default of each compression.
+	DefaultLevel = 0
+	// BlockSize is the size of each gzip block compressed in parallel.
+	BlockSize = 1 << 20
+)
+
+// CompressionByExt returns the compression of a path by its extension: ".gz"
+// for Gzip, ".bz2" for Bzip2, ".zst" for Zstd and Plain otherwise.
+func CompressionByExt(path string) Compression {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz":
+		return Gzip
+	case ".bz2":
+		return Bzip2
+	case ".zst":
+		return Zstd
+	}
+	return Plain
+}
+
+// nopCloser is a WriteCloser whose Close does nothing.
+type nopCloser struct {
+	io.Writer
+}
+
+// Close does nothing and returns nil.
+func (nopCloser) Close() error { return nil }
+
+// NewCompressor returns a WriteCloser that compresses to f.
+//
+// Level ranges from 1 to 9 for Gzip and Bzip2, and 1 to 22 for Zstd.
+// DefaultLevel selects the default of the compression. Gzip compresses blocks
+// of BlockSize on all CPUs in parallel.
+//
+// Close flushes the compressed stream. It does not close f.
+func NewCompressor(f io.Writer, c Compression, level int) (io.WriteCloser, error) {
+	switch c {
+	case Plain:
+		return nopCloser{f}, nil
+	case Gzip:
+		if level == DefaultLevel {
+			level = pgzip.DefaultCompression
+		}
+
+		w, err := pgzip.NewWriterLevel(f, level)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := w.SetConcurrency(BlockSize, runtime.GOMAXPROCS(0)); err != nil {
+			return nil, err
+		}
+
+		return w, nil
+	case Bzip2:
+		return bzip2.NewWriter(f, &bzip2.WriterConfig{Level: level})
+	case Zstd:
+		opts := []zstd.EOption{}
+
+		if level != DefaultLevel {
+			if level < 1 || level > 22 {
+				return nil, fmt.Errorf("zstd: invalid compression level: %d", level)
+			}
+			opts = append(
+				opts,
+				zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)),
+			)
+		}
+
+		return zstd.NewWriter(f, opts...)
+	}
+	return nil, fmt.Errorf("unknown compression %v", c)
+}
@@ -0,0 +1,77 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package seqio_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/seqio"
+)
+
+func TestCompressionByExt(t *testing.T) {
+	for path, exp := range map[string]seqio.Compression{
+		"foo.fasta":     seqio.Plain,
+		"foo.fasta.gz":  seqio.Gzip,
+		"foo.FASTQ.GZ":  seqio.Gzip,
+		"foo.fastq.bz2": seqio.Bzip2,
+		"foo.fa.zst":    seqio.Zstd,
+		"":              seqio.Plain,
+	} {
+		assert.Equal(t, exp, seqio.CompressionByExt(path),
+			"Compression should be chosen by the extension.",
+		)
+	}
+}
+
+func TestNewCompressor(t *testing.T) {
+	in := ">Foo\nACGT\n"
+
+	for _, c := range []seqio.Compression{
+		seqio.Plain, seqio.Gzip, seqio.Bzip2, seqio.Zstd,
+	} {
+		for _, level := range []int{seqio.DefaultLevel, 1, 9} {
+			f := new(bytes.Buffer)
+
+			w, err := seqio.NewCompressor(f, c, level)
+
+			if !assert.NoError(t, err) {
+				continue
+			}
+
+			w.Write([]byte(in))
+
+			if !assert.NoError(t, w.Close()) {
+				continue
+			}
+
+			assertOpen(t, f, c, seqio.FASTA)
+		}
+	}
+}
+
+func TestNewCompressorLevel(t *testing.T) {
+	for _, c := range []seqio.Compression{
+		seqio.Gzip, seqio.Bzip2, seqio.Zstd,
+	} {
+		_, err := seqio.NewCompressor(new(bytes.Buffer), c, 42)
+
+		assert.Error(t, err, "Invalid level should return an error.")
+	}
+}
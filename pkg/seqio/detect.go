@@ -0,0 +1,197 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package seqio
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the compression of a sequence stream.
+type Compression int
+
+const (
+	// Plain is a stream without compression.
+	Plain Compression = iota
+	// Gzip is a stream compressed by gzip.
+	Gzip
+	// Bzip2 is a stream compressed by bzip2.
+	Bzip2
+	// Zstd is a stream compressed by Zstandard.
+	Zstd
+)
+
+// String returns the name of the compression.
+func (c Compression) String() string {
+	switch c {
+	case Plain:
+		return "plain"
+	case Gzip:
+		return "gzip"
+	case Bzip2:
+		return "bzip2"
+	case Zstd:
+		return "zstd"
+	}
+	return fmt.Sprintf("Compression(%d)", int(c))
+}
+
+// Magic numbers at the start of compressed streams.
+var (
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicBzip2 = []byte("BZh")
+	magicZstd  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Input is a Reader of a sequence stream whose compression and format are
+// detected by Open.
+type Input struct {
+	Reader
+	Format      Format
+	Molecule    Molecule
+	Compression Compression
+	closer      io.Closer
+	counter     *counter
+	buf         *bufio.Reader
+}
+
+// counter counts the bytes read from a reader.
+type counter struct {
+	r io.Reader
+	n int64
+}
+
+// Read reads from the underlaying reader and counts the bytes.
+func (c *counter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Offset returns the number of bytes of the decompressed stream consumed by
+// the Input.
+func (in *Input) Offset() int64 {
+	return in.counter.n - int64(in.buf.Buffered())
+}
+
+// Close releases the decompressor of the Input. It does not close the
+// underlaying reader.
+func (in *Input) Close() error {
+	if in.closer != nil {
+		return in.closer.Close()
+	}
+	return nil
+}
+
+// Open detects the compression and the format of a stream and returns an
+// Input of its sequences.
+//
+// The compression is detected by the magic number at the start of the stream.
+// If format is Auto, the format is detected by the first character of the
+// decompressed stream that is not a space: ">" for FASTA and "@" for FASTQ.
+// An empty stream is read as FASTA without any sequence.
+//
+// If mol is AutoMolecule, the molecule is detected by the letters of the
+// sequences buffered at the start of the stream, and an empty stream is read as
+// DNA.
+func Open(f io.Reader, format Format, mol Molecule, enc alphabet.Encoding) (*Input, error) {
+	in := Input{Format: format, Molecule: mol}
+
+	br := bufio.NewReader(f)
+
+	var r io.Reader = br
+
+	switch magic, _ := br.Peek(len(magicZstd)); {
+	case bytes.HasPrefix(magic, magicGzip):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip stream: %w", err)
+		}
+		in.Compression, in.closer, r = Gzip, zr, zr
+	case bytes.HasPrefix(magic, magicBzip2):
+		in.Compression, r = Bzip2, bzip2.NewReader(br)
+	case bytes.HasPrefix(magic, magicZstd):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read zstd stream: %w", err)
+		}
+		in.Compression, in.closer, r = Zstd, zr.IOReadCloser(), zr
+	}
+
+	// The readers of biogo use buf as is, so the bytes consumed by them are
+	// the bytes counted minus the bytes buffered.
+	in.counter = &counter{r: r}
+	in.buf = bufio.NewReader(in.counter)
+
+	if in.Format == Auto {
+		detected, err := detectFormat(in.buf)
+		if err != nil {
+			in.Close()
+			return nil, err
+		}
+		in.Format = detected
+	}
+
+	if in.Molecule == AutoMolecule {
+		// Peek returns the bytes buffered with an error at the end of a short
+		// stream.
+		b, _ := in.buf.Peek(in.buf.Size())
+		in.Molecule = detectMolecule(b, in.Format)
+	}
+
+	in.Reader = newReader(in.buf, in.Format, in.Molecule.Alphabet(), enc)
+
+	return &in, nil
+}
+
+// detectFormat detects the format of a stream by its first character that is
+// not a space. The leading spaces are discarded, so a stream of any number of
+// blank lines before its first record is detected as well.
+func detectFormat(br *bufio.Reader) (Format, error) {
+	for {
+		b, err := br.Peek(1)
+
+		if len(b) == 0 {
+			if err == io.EOF {
+				return FASTA, nil
+			}
+			return Auto, fmt.Errorf("failed to detect format: %w", err)
+		}
+
+		switch c := b[0]; c {
+		case ' ', '\t', '\r', '\n':
+			if _, err := br.Discard(1); err != nil {
+				return Auto, fmt.Errorf("failed to detect format: %w", err)
+			}
+		case '>':
+			return FASTA, nil
+		case '@':
+			return FASTQ, nil
+		default:
+			return Auto, fmt.Errorf(
+				"failed to detect format: unexpected character %q", c,
+			)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package seqio_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/seqio"
+)
+
+// bzip2Fasta is ">Foo\nACGT\n" compressed by bzip2.
+var bzip2Fasta = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x6f, 0x47,
+	0x52, 0x6f, 0x00, 0x00, 0x01, 0x4f, 0x00, 0x00, 0x10, 0x00, 0x01, 0x29,
+	0x80, 0x04, 0x00, 0x00, 0x00, 0xa0, 0x00, 0x31, 0x0c, 0x08, 0x21, 0xa3,
+	0x27, 0xa9, 0xc6, 0x53, 0x70, 0x87, 0x8b, 0xb9, 0x22, 0x9c, 0x28, 0x48,
+	0x37, 0xa3, 0xa9, 0x37, 0x80,
+}
+
+// assertOpen opens a stream and checks its compression, format and first
+// sequence.
+func assertOpen(
+	t *testing.T, f io.Reader, c seqio.Compression, format seqio.Format,
+) {
+	in, err := seqio.Open(f, seqio.Auto, seqio.AutoMolecule, seqio.Phred33)
+
+	if assert.NoError(t, err) {
+		defer in.Close()
+
+		assert.Equal(t, c, in.Compression,
+			"Compression should be detected.",
+		)
+		assert.Equal(t, format, in.Format, "Format should be detected.")
+
+		s, err := in.Read()
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Foo", s.Name(), "Name should be the ID.")
+			assert.Equal(t, "ACGT", seqio.AsSeq(s).String(),
+				"Letters should be the sequence.",
+			)
+		}
+	}
+}
+
+func TestOpenPlain(t *testing.T) {
+	assertOpen(t, bytes.NewBufferString("\n>Foo\nACGT\n"),
+		seqio.Plain, seqio.FASTA,
+	)
+	assertOpen(t, bytes.NewBufferString("@Foo\nACGT\n+\nIIII\n"),
+		seqio.Plain, seqio.FASTQ,
+	)
+
+	blank := strings.Repeat("\n", 8192)
+
+	assertOpen(t, bytes.NewBufferString(blank+"@Foo\nACGT\n+\nIIII\n"),
+		seqio.Plain, seqio.FASTQ,
+	)
+}
+
+func TestOpenGzip(t *testing.T) {
+	f := new(bytes.Buffer)
+
+	w := gzip.NewWriter(f)
+	w.Write([]byte("@Foo\nACGT\n+\nIIII\n"))
+	w.Close()
+
+	assertOpen(t, f, seqio.Gzip, seqio.FASTQ)
+}
+
+func TestOpenBzip2(t *testing.T) {
+	assertOpen(t, bytes.NewBuffer(bzip2Fasta), seqio.Bzip2, seqio.FASTA)
+}
+
+func TestOpenZstd(t *testing.T) {
+	f := new(bytes.Buffer)
+
+	w, _ := zstd.NewWriter(f)
+	w.Write([]byte(">Foo\nACGT\n"))
+	w.Close()
+
+	assertOpen(t, f, seqio.Zstd, seqio.FASTA)
+}
+
+func TestOpenFormat(t *testing.T) {
+	in, err := seqio.Open(
+		bytes.NewBufferString("@Foo\nACGT\n+\nIIII\n"),
+		seqio.FASTA, seqio.DNA, seqio.Phred33,
+	)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, seqio.FASTA, in.Format,
+			"Format should not be detected when it is given.",
+		)
+	}
+}
+
+func TestOpenEmpty(t *testing.T) {
+	in, err := seqio.Open(new(bytes.Buffer), seqio.Auto, seqio.AutoMolecule, seqio.Phred33)
+
+	if assert.NoError(t, err) {
+		_, err := in.Read()
+
+		assert.Equal(t, io.EOF, err, "An empty stream has no sequence.")
+	}
+}
+
+func TestOpenUnknown(t *testing.T) {
+	in, err := seqio.Open(
+		bytes.NewBufferString("LOCUS       NM_000518\n"),
+		seqio.Auto, seqio.AutoMolecule, seqio.Phred33,
+	)
+
+	if assert.Error(t, err) {
+		assert.Nil(t, in, "nil should be returned when an error occurs.")
+	}
+}
+
+func TestOpenMalformGzip(t *testing.T) {
+	in, err := seqio.Open(
+		bytes.NewBuffer([]byte{0x1f, 0x8b, 0x00}),
+		seqio.Auto, seqio.AutoMolecule, seqio.Phred33,
+	)
+
+	if assert.Error(t, err) {
+		assert.Nil(t, in, "nil should be returned when an error occurs.")
+	}
+}
+
+func TestParseMolecule(t *testing.T) {
+	for name, exp := range map[string]seqio.Molecule{
+		"auto":    seqio.AutoMolecule,
+		"dna":     seqio.DNA,
+		"rna":     seqio.RNA,
+		"protein": seqio.Protein,
+	} {
+		m, err := seqio.ParseMolecule(name)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, exp, m, "Molecule should be parsed from its name.")
+			assert.Equal(t, name, m.String(), "Name should be the same.")
+		}
+	}
+
+	_, err := seqio.ParseMolecule("lipid")
+
+	assert.Error(t, err, "Unknown molecule should return an error.")
+}
+
+func TestOpenMolecule(t *testing.T) {
+	for _, c := range []struct {
+		in  string
+		mol seqio.Molecule
+		exp seqio.Molecule
+	}{
+		{">Foo\nACGTNACGTA\nRACGTACGTT\n", seqio.AutoMolecule, seqio.DNA},
+		{">Foo\nMKTAYIAKQR\n>Bar\nQISFVKSHFS\n", seqio.AutoMolecule, seqio.Protein},
+		{"@Foo\nACGUU\n+\nIIIII\n", seqio.AutoMolecule, seqio.RNA},
+		{"@ACGT\nMKTAY\n+\nIIIII\n", seqio.AutoMolecule, seqio.Protein},
+		{">Foo\nMKTAYIAKQR\n", seqio.DNA, seqio.DNA},
+		{"", seqio.AutoMolecule, seqio.DNA},
+	} {
+		in, err := seqio.Open(
+			bytes.NewBufferString(c.in), seqio.Auto, c.mol, seqio.Phred33,
+		)
+
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		assert.Equal(t, c.exp, in.Molecule, "Molecule of %q should be %v.", c.in, c.exp)
+
+		if s, err := in.Read(); err == nil {
+			assert.Equal(t, c.exp.Alphabet(), s.Alphabet(),
+				"Sequences should be read over the alphabet of the molecule.",
+			)
+		}
+	}
+}
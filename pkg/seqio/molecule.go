@@ -0,0 +1,126 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package seqio
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/biogo/biogo/alphabet"
+)
+
+// Molecule is the kind of letters of a sequence stream.
+type Molecule int
+
+const (
+	// AutoMolecule is a stream whose molecule is detected by Open.
+	AutoMolecule Molecule = iota - 1
+	// DNA is a stream of nucleotides of DNA.
+	DNA
+	// RNA is a stream of nucleotides of RNA.
+	RNA
+	// Protein is a stream of amino acids.
+	Protein
+)
+
+// NucleotideRatio is the minimal ratio of A, C, G, T, U and N in the letters
+// of a stream detected as nucleotides.
+const NucleotideRatio = 0.9
+
+// String returns the name of the molecule.
+func (m Molecule) String() string {
+	switch m {
+	case AutoMolecule:
+		return "auto"
+	case DNA:
+		return "dna"
+	case RNA:
+		return "rna"
+	case Protein:
+		return "protein"
+	}
+	return fmt.Sprintf("Molecule(%d)", int(m))
+}
+
+// ParseMolecule parses the name of a molecule.
+func ParseMolecule(name string) (Molecule, error) {
+	switch strings.ToLower(name) {
+	case "auto":
+		return AutoMolecule, nil
+	case "dna":
+		return DNA, nil
+	case "rna":
+		return RNA, nil
+	case "protein", "aa":
+		return Protein, nil
+	}
+	return DNA, fmt.Errorf("unknown molecule %q", name)
+}
+
+// Alphabet returns the alphabet of the sequences of the molecule. AutoMolecule
+// is read as DNA.
+func (m Molecule) Alphabet() alphabet.Alphabet {
+	switch m {
+	case RNA:
+		return alphabet.RNAgapped
+	case Protein:
+		return alphabet.Protein
+	}
+	return alphabet.DNAgapped
+}
+
+// detectMolecule detects the molecule of the sequences at the start of a
+// stream of the format. The stream is nucleotides if at least NucleotideRatio
+// of its letters are A, C, G, T, U or N, and RNA if it has more U than T.
+func detectMolecule(b []byte, format Format) Molecule {
+	var n, nuc, t, u int
+
+	for i, line := range bytes.Split(b, []byte{'\n'}) {
+		switch {
+		case format == FASTQ && i%4 != 1:
+			continue
+		case format != FASTQ && (len(line) == 0 || line[0] == '>' || line[0] == ';'):
+			continue
+		}
+
+		for _, c := range line {
+			switch c | 0x20 {
+			case 'a', 'c', 'g', 'n':
+				nuc++
+			case 't':
+				nuc++
+				t++
+			case 'u':
+				nuc++
+				u++
+			}
+
+			if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' {
+				n++
+			}
+		}
+	}
+
+	switch {
+	case float64(nuc) < NucleotideRatio*float64(n):
+		return Protein
+	case u > t:
+		return RNA
+	}
+	return DNA
+}
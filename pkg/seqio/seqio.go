@@ -18,13 +18,18 @@
 package seqio
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"log"
+	"strings"
 	"sync"
 
 	"github.com/biogo/biogo/alphabet"
 	"github.com/biogo/biogo/io/seqio"
 	"github.com/biogo/biogo/io/seqio/fasta"
+	"github.com/biogo/biogo/io/seqio/fastq"
+	"github.com/biogo/biogo/seq"
 	"github.com/biogo/biogo/seq/linear"
 )
 
@@ -33,7 +38,122 @@ const (
 	WidthCol = 80
 )
 
-// ReadSeq reads a sequence from a FASTA file.
+const (
+	// Phred33 is the quality encoding of Sanger and Illumina 1.8+ FASTQ.
+	Phred33 = alphabet.Sanger
+	// Phred64 is the quality encoding of Illumina 1.3+ FASTQ.
+	Phred64 = alphabet.Illumina1_3
+)
+
+// Format is the file format of a sequence stream.
+type Format int
+
+const (
+	// Auto is a stream whose format is detected by Open.
+	Auto Format = iota - 1
+	// FASTA is a stream of FASTA records without quality.
+	FASTA
+	// FASTQ is a stream of FASTQ records with quality.
+	FASTQ
+)
+
+// String returns the name of the format.
+func (f Format) String() string {
+	switch f {
+	case Auto:
+		return "auto"
+	case FASTA:
+		return "fasta"
+	case FASTQ:
+		return "fastq"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// ParseFormat parses the name of a format.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "auto":
+		return Auto, nil
+	case "fasta", "fa":
+		return FASTA, nil
+	case "fastq", "fq":
+		return FASTQ, nil
+	}
+	return FASTA, fmt.Errorf("unknown format %q", name)
+}
+
+// ParseEncoding returns the quality encoding of a Phred offset.
+//
+// Only offsets 33 and 64 are recognized.
+func ParseEncoding(offset int) (alphabet.Encoding, error) {
+	switch offset {
+	case 33:
+		return Phred33, nil
+	case 64:
+		return Phred64, nil
+	}
+	return alphabet.None, fmt.Errorf("unknown Phred offset %d", offset)
+}
+
+// Reader is a reader of sequences. It is satisfied by the readers of biogo.
+type Reader interface {
+	Read() (seq.Sequence, error)
+}
+
+// Writer is a writer of sequences. It is satisfied by the writers of biogo.
+type Writer interface {
+	Write(seq.Sequence) (int, error)
+}
+
+// NewReader returns a Reader of the format.
+//
+// FASTA records are read as linear.Seq. FASTQ records are read as linear.QSeq
+// with the qualities decoded by enc. The records are read as DNA.
+func NewReader(f io.Reader, format Format, enc alphabet.Encoding) Reader {
+	return newReader(f, format, alphabet.DNAgapped, enc)
+}
+
+// newReader returns a Reader of the format whose records are read over alpha.
+func newReader(f io.Reader, format Format, alpha alphabet.Alphabet, enc alphabet.Encoding) Reader {
+	if format == FASTQ {
+		t := linear.NewQSeq("", nil, alpha, enc)
+		return fastq.NewReader(f, t)
+	}
+	return fasta.NewReader(f, linear.NewSeq("", nil, alpha))
+}
+
+// NewWriter returns a Writer of the format.
+//
+// A FASTQ Writer encodes the qualities with the encoding of each sequence, or
+// Phred33 if the sequence does not have one. A sequence without quality is
+// written with the default quality of biogo.
+func NewWriter(f io.Writer, format Format) Writer {
+	if format == FASTQ {
+		return fastq.NewWriter(f)
+	}
+	return fasta.NewWriter(f, WidthCol)
+}
+
+// AsSeq returns the letters and the annotation of a sequence as a linear.Seq.
+//
+// If s is a linear.Seq, AsSeq returns s itself.
+func AsSeq(s seq.Sequence) *linear.Seq {
+	if l, ok := s.(*linear.Seq); ok {
+		return l
+	}
+
+	l := make(alphabet.Letters, s.Len())
+
+	for i := range l {
+		l[i] = s.At(i).L
+	}
+
+	return &linear.Seq{Annotation: *s.CloneAnnotation(), Seq: l}
+}
+
+// ReadSeq reads a sequence from a FASTA file as DNA. Use Open to read the
+// other molecules.
 //
 // If the underlaying reader has encountered any error, ReadSeq will return the
 // error.
@@ -52,7 +172,8 @@ func ReadSeq(f io.Reader) (*linear.Seq, error) {
 	return seq, nil
 }
 
-// ScanSeq scans sequences from a fasta file to a channel.
+// ScanSeq scans sequences from a fasta file to a channel as DNA. Use Open to
+// read the other molecules.
 //
 // If the underlaying reader has encountered any error, ScanSeq will panic as
 // the reader can no longer be read.
@@ -90,3 +211,28 @@ func WriteSeq(f io.Writer, in <-chan *linear.Seq, wg *sync.WaitGroup) {
 		}
 	}
 }
+
+// Scan scans sequences from a Reader to a channel.
+//
+// If the Reader has encountered any error, Scan will panic as the reader can
+// no longer be read. Use ScanContext to handle the error instead.
+func Scan(r Reader, out chan<- seq.Sequence, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	if err := ScanContext(context.Background(), r, out); err != nil {
+		close(out)
+		log.Panicf("Error occurred during scan: %s", err)
+	}
+}
+
+// Write writes sequences from a channel to a Writer.
+//
+// If the Writer has encountered any error, Write will panic as the writer can
+// no longer be written. Use WriteContext to handle the error instead.
+func Write(w Writer, in <-chan seq.Sequence, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	if err := WriteContext(context.Background(), w, in); err != nil {
+		log.Panicf("Error occurred during write: %s", err)
+	}
+}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/biogo/biogo/alphabet"
 	"github.com/biogo/biogo/io/seqio/fasta"
+	"github.com/biogo/biogo/seq"
 	"github.com/biogo/biogo/seq/linear"
 
 	"github.com/stretchr/testify/assert"
@@ -260,3 +261,184 @@ func TestWriteSeqWriterError(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestParseFormat(t *testing.T) {
+	for name, exp := range map[string]seqio.Format{
+		"auto":  seqio.Auto,
+		"fasta": seqio.FASTA,
+		"FA":    seqio.FASTA,
+		"fastq": seqio.FASTQ,
+		"fq":    seqio.FASTQ,
+	} {
+		f, err := seqio.ParseFormat(name)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, exp, f,
+				"Format should be parsed from its name.",
+			)
+		}
+	}
+
+	_, err := seqio.ParseFormat("genbank")
+
+	assert.Error(t, err, "Unknown format should return an error.")
+}
+
+func TestParseEncoding(t *testing.T) {
+	enc, err := seqio.ParseEncoding(33)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, seqio.Phred33, enc, "33 should be Phred+33.")
+	}
+
+	enc, err = seqio.ParseEncoding(64)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, seqio.Phred64, enc, "64 should be Phred+64.")
+	}
+
+	_, err = seqio.ParseEncoding(59)
+
+	assert.Error(t, err, "Unknown offset should return an error.")
+}
+
+func TestNewReaderFASTQ(t *testing.T) {
+	encs := map[alphabet.Encoding]string{
+		seqio.Phred33: "@Foo\nACGT\n+\n!+5I\n",
+		seqio.Phred64: "@Foo\nACGT\n+\n@JTh\n",
+	}
+
+	for enc, in := range encs {
+		r := seqio.NewReader(bytes.NewBufferString(in), seqio.FASTQ, enc)
+
+		s, err := r.Read()
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Foo", s.Name(), "Name should be the ID.")
+			assert.Equal(t, "ACGT", seqio.AsSeq(s).String(),
+				"Letters should be the FASTQ sequence.",
+			)
+			for i, q := range []alphabet.Qphred{0, 10, 20, 40} {
+				assert.Equal(t, q, s.At(i).Q,
+					"Quality should be decoded by the encoding.",
+				)
+			}
+		}
+	}
+}
+
+func TestNewWriterFASTQ(t *testing.T) {
+	in := "@Foo\nACGT\n+\n!+5I\n"
+
+	s, err := seqio.NewReader(
+		bytes.NewBufferString(in), seqio.FASTQ, seqio.Phred33,
+	).Read()
+
+	if assert.NoError(t, err) {
+		f := new(bytes.Buffer)
+
+		_, err := seqio.NewWriter(f, seqio.FASTQ).Write(s)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, in, f.String(),
+				"Output should be the same as input sequence.",
+			)
+		}
+	}
+}
+
+func TestAsSeq(t *testing.T) {
+	seq := linear.NewSeq("Foo", []alphabet.Letter("ACGT"), alphabet.DNA)
+
+	assert.True(t, seq == seqio.AsSeq(seq),
+		"A linear.Seq should be returned as is.",
+	)
+
+	qseq := linear.NewQSeq(
+		"Foo",
+		[]alphabet.QLetter{{L: 'A', Q: 10}, {L: 'N', Q: 0}},
+		alphabet.DNA,
+		seqio.Phred33,
+	)
+
+	res := seqio.AsSeq(qseq)
+
+	assert.Equal(t, "Foo", res.ID, "ID should be the same as input.")
+	assert.Equal(t, "AN", res.String(),
+		"Letters should not be filtered by quality.",
+	)
+}
+
+func TestScan(t *testing.T) {
+	f := bytes.NewBufferString("@Foo\nAAAA\n+\nIIII\n@Bar\nGGGG\n+\nIIII\n")
+
+	c := make(chan seq.Sequence)
+
+	wg.Add(1)
+
+	go seqio.Scan(seqio.NewReader(f, seqio.FASTQ, seqio.Phred33), c, &wg)
+
+	var names []string
+
+	for s := range c {
+		names = append(names, s.Name())
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, []string{"Foo", "Bar"}, names,
+		"Sequences should be scanned in order.",
+	)
+}
+
+func TestScanMalform(t *testing.T) {
+	f := bytes.NewBufferString("@Foo\nAAAA\n+\nIII\n")
+
+	c := make(chan seq.Sequence)
+
+	wg.Add(1)
+
+	go assert.Panics(t, func() {
+		seqio.Scan(seqio.NewReader(f, seqio.FASTQ, seqio.Phred33), c, &wg)
+	},
+		"Scan should panic when encountered an error",
+	)
+
+	s := <-c
+
+	assert.Nil(t, s,
+		"nil should be returned when an error occurs.",
+	)
+
+	wg.Wait()
+}
+
+func TestWrite(t *testing.T) {
+	seqs := []*linear.Seq{
+		linear.NewSeq("Foo", []alphabet.Letter("AAAA"), alphabet.DNA),
+		linear.NewSeq("Bar", []alphabet.Letter("GGGG"), alphabet.DNA),
+	}
+
+	c := make(chan seq.Sequence)
+
+	f := new(bytes.Buffer)
+
+	wg.Add(1)
+
+	go seqio.Write(seqio.NewWriter(f, seqio.FASTA), c, &wg)
+
+	var fExp string
+
+	for _, s := range seqs {
+		c <- s
+		fExp += writeString(s)
+	}
+
+	close(c)
+
+	wg.Wait()
+
+	assert.Equal(t, fExp, f.String(),
+		"Output should be the same as input sequence.",
+	)
+}
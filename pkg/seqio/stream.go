@@ -0,0 +1,106 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package seqio
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/biogo/biogo/seq"
+)
+
+// ParseError is an error of reading a record from a sequence stream.
+type ParseError struct {
+	// Record is the 1-based index of the record being read.
+	Record int
+	// Offset is the number of bytes of the decompressed stream consumed when
+	// the error occurred, or -1 if the reader does not report it.
+	Offset int64
+	Err    error
+}
+
+// Error returns the error message with the record and the offset.
+func (e *ParseError) Error() string {
+	if e.Offset < 0 {
+		return fmt.Sprintf("record %d: %v", e.Record, e.Err)
+	}
+	return fmt.Sprintf("record %d at byte %d: %v", e.Record, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlaying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// offsetter is a reader that reports the bytes consumed, such as Input.
+type offsetter interface {
+	Offset() int64
+}
+
+// ScanContext scans sequences from a Reader to a channel.
+//
+// ScanContext closes out and returns nil when the Reader reaches the end of
+// the stream. If the Reader encounters an error, ScanContext returns a
+// ParseError. If ctx is done, ScanContext returns the error of ctx. In both
+// cases out is left open, so the receivers should also watch ctx.
+func ScanContext(ctx context.Context, r Reader, out chan<- seq.Sequence) error {
+	for n := 1; ; n++ {
+		s, err := r.Read()
+
+		if err == io.EOF {
+			close(out)
+			return nil
+		} else if err != nil {
+			e := ParseError{Record: n, Offset: -1, Err: err}
+
+			if o, ok := r.(offsetter); ok {
+				e.Offset = o.Offset()
+			}
+
+			return &e
+		}
+
+		select {
+		case out <- s:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// WriteContext writes sequences from a channel to a Writer.
+//
+// WriteContext returns nil after in is closed and all sequences are written.
+// If the Writer encounters an error or ctx is done, WriteContext returns the
+// error.
+func WriteContext(ctx context.Context, w Writer, in <-chan seq.Sequence) error {
+	for {
+		select {
+		case s, ok := <-in:
+			if !ok {
+				return nil
+			}
+
+			if _, err := w.Write(s); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package seqio_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mys721tx/gsearch/mocks"
+
+	"github.com/mys721tx/gsearch/pkg/seqio"
+)
+
+func TestScanContext(t *testing.T) {
+	f := bytes.NewBufferString(">Foo\nAAAA\n>Bar\nGGGG\n")
+
+	in, _ := seqio.Open(f, seqio.Auto, seqio.AutoMolecule, seqio.Phred33)
+
+	c := make(chan seq.Sequence)
+	errs := make(chan error)
+
+	go func() { errs <- seqio.ScanContext(context.Background(), in, c) }()
+
+	var names []string
+
+	for s := range c {
+		names = append(names, s.Name())
+	}
+
+	assert.NoError(t, <-errs, "No error should be returned.")
+	assert.Equal(t, []string{"Foo", "Bar"}, names,
+		"Sequences should be scanned in order.",
+	)
+}
+
+func TestScanContextMalform(t *testing.T) {
+	fIn := "@Foo\nAAAA\n+\nIIII\n@Bar\nAAAA\n+\nIII\n"
+
+	in, _ := seqio.Open(bytes.NewBufferString(fIn), seqio.Auto, seqio.AutoMolecule, seqio.Phred33)
+
+	c := make(chan seq.Sequence, 2)
+
+	err := seqio.ScanContext(context.Background(), in, c)
+
+	var e *seqio.ParseError
+
+	if assert.True(t, errors.As(err, &e), "A ParseError should be returned.") {
+		assert.Equal(t, 2, e.Record, "Record should be the failed one.")
+		assert.Equal(t, int64(len(fIn)), e.Offset,
+			"Offset should be the bytes consumed.",
+		)
+		assert.Contains(t, e.Error(), "record 2 at byte 33",
+			"Error should report the record and the offset.",
+		)
+	}
+
+	assert.Len(t, c, 1, "Only the first sequence should be scanned.")
+}
+
+func TestScanContextReaderError(t *testing.T) {
+	f := new(mocks.Reader)
+
+	f.On("Read", mock.Anything).Return(0, os.ErrPermission)
+
+	r := seqio.NewReader(f, seqio.FASTA, seqio.Phred33)
+
+	err := seqio.ScanContext(context.Background(), r, make(chan seq.Sequence))
+
+	var e *seqio.ParseError
+
+	if assert.True(t, errors.As(err, &e), "A ParseError should be returned.") {
+		assert.Equal(t, int64(-1), e.Offset,
+			"Offset should be -1 when the reader does not report it.",
+		)
+		assert.True(t, errors.Is(err, os.ErrPermission),
+			"The error of the reader should be wrapped.",
+		)
+	}
+}
+
+func TestScanContextCancel(t *testing.T) {
+	f := bytes.NewBufferString(">Foo\nAAAA\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := seqio.ScanContext(
+		ctx, seqio.NewReader(f, seqio.FASTA, seqio.Phred33),
+		make(chan seq.Sequence),
+	)
+
+	assert.Equal(t, context.Canceled, err,
+		"The error of the context should be returned.",
+	)
+}
+
+func TestWriteContext(t *testing.T) {
+	s := linear.NewSeq("Foo", []alphabet.Letter("AAAA"), alphabet.DNA)
+
+	c := make(chan seq.Sequence, 1)
+	c <- s
+	close(c)
+
+	f := new(bytes.Buffer)
+
+	err := seqio.WriteContext(
+		context.Background(), seqio.NewWriter(f, seqio.FASTA), c,
+	)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, writeString(s), f.String(),
+			"Output should be the same as input sequence.",
+		)
+	}
+}
+
+func TestWriteContextWriterError(t *testing.T) {
+	s := linear.NewSeq("Foo", []alphabet.Letter("AAAA"), alphabet.DNA)
+
+	f := new(mocks.Writer)
+
+	f.On("Write", mock.Anything).Return(0, os.ErrClosed)
+
+	c := make(chan seq.Sequence, 1)
+	c <- s
+
+	err := seqio.WriteContext(
+		context.Background(), seqio.NewWriter(f, seqio.FASTA), c,
+	)
+
+	assert.Equal(t, os.ErrClosed, err,
+		"The error of the writer should be returned.",
+	)
+}
+
+func TestWriteContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := seqio.WriteContext(
+		ctx, seqio.NewWriter(new(bytes.Buffer), seqio.FASTA),
+		make(chan seq.Sequence),
+	)
+
+	assert.Equal(t, context.Canceled, err,
+		"The error of the context should be returned.",
+	)
+}
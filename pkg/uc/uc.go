@@ -0,0 +1,146 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package uc provides a writer of the tab-separated UC format of USEARCH and
+// vsearch, which maps each sequence to its cluster, or each query to its
+// target in a search.
+//
+// Each line is a record of ten fields:
+//
+//	1	type: S for a centroid, H for a hit, C for a cluster and N for a
+//		query without hit.
+//	2	the number of the cluster or the target, from 0, or "*" for N.
+//	3	the length of the sequence, or the number of members for C.
+//	4	the percent identity to the centroid for H, or "*".
+//	5	the strand of the hit to the centroid for H, or "*".
+//	6	unused, 0 for H or "*".
+//	7	unused, 0 for H or "*".
+//	8	the compressed alignment for H, or "*" if the sequences are not
+//		aligned.
+//	9	the label of the sequence.
+//	10	the label of the centroid for H, or "*".
+package uc
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/biogo/biogo/seq"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+)
+
+// Writer writes clusters as UC records.
+type Writer struct {
+	w io.Writer
+	n int
+}
+
+// NewWriter returns a Writer writing to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes the records of a cluster: an S record of its first member, an
+// H record of each other member and a C record of the cluster. The label of
+// the centroid in the S, H and C records is the name of the cluster, so it
+// follows the relabeling of the cluster.
+func (w *Writer) Write(c *cluster.Cluster) error {
+	if len(c.Merged) == 0 {
+		return fmt.Errorf("uc: cluster %q has no member", c.ID)
+	}
+
+	label := c.Name()
+
+	_, err := fmt.Fprintf(
+		w.w, "S\t%d\t%d\t*\t*\t*\t*\t*\t%s\t*\n", w.n, c.Merged[0].Len, label,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	for _, m := range c.Merged[1:] {
+		err := w.WriteHit(Hit{
+			Cluster:  w.n,
+			Len:      m.Len,
+			Identity: m.Identity,
+			Strand:   m.Strand,
+			Query:    m.ID,
+			Target:   label,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(
+		w.w, "C\t%d\t%d\t*\t*\t*\t*\t*\t%s\t*\n", w.n, len(c.Merged), label,
+	)
+
+	w.n++
+
+	return err
+}
+
+// Hit is an H record of a sequence hitting a centroid or a target.
+type Hit struct {
+	// Cluster is the number of the cluster or the target, from 0.
+	Cluster int
+	// Len is the length of the sequence.
+	Len int
+	// Identity is the percent identity to the target.
+	Identity float64
+	// Strand is the strand of the sequence hitting the target.
+	Strand seq.Strand
+	// Alignment is the compressed alignment, or empty if the sequences are
+	// not aligned.
+	Alignment string
+	// Query and Target are the labels of the sequence and the target.
+	Query, Target string
+}
+
+// WriteHit writes an H record of a hit.
+func (w *Writer) WriteHit(h Hit) error {
+	strand := '+'
+
+	if h.Strand == seq.Minus {
+		strand = '-'
+	}
+
+	aln := h.Alignment
+
+	if aln == "" {
+		aln = "*"
+	}
+
+	_, err := fmt.Fprintf(
+		w.w, "H\t%d\t%d\t%.1f\t%c\t0\t0\t%s\t%s\t%s\n",
+		h.Cluster, h.Len, h.Identity, strand, aln, h.Query, h.Target,
+	)
+
+	return err
+}
+
+// WriteNoHit writes an N record of a query of length n without hit.
+func (w *Writer) WriteNoHit(query string, n int) error {
+	_, err := fmt.Fprintf(
+		w.w, "N\t*\t%d\t*\t*\t*\t*\t*\t%s\t*\n", n, query,
+	)
+
+	return err
+}
@@ -0,0 +1,119 @@
+// GSEARCH: A concurrent tool suite for metagenomics
+// Copyright (C) 2018  Yishen Miao
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package uc_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/biogo/biogo/alphabet"
+	"github.com/biogo/biogo/seq"
+	"github.com/biogo/biogo/seq/linear"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mys721tx/gsearch/pkg/cluster"
+	"github.com/mys721tx/gsearch/pkg/uc"
+)
+
+func TestWrite(t *testing.T) {
+	c := cluster.ParseAnno(
+		linear.NewSeq("foo;size=2", []alphabet.Letter("ACGT"), alphabet.DNA),
+	)
+
+	o := cluster.ParseAnno(
+		linear.NewSeq("bar;size=1", []alphabet.Letter("ACG"), alphabet.DNA),
+	)
+	o.Merged[0].Strand = seq.Minus
+	o.Merged[0].Identity = 97.25
+
+	c.Merge(o, nil)
+
+	d := cluster.ParseAnno(
+		linear.NewSeq("baz", []alphabet.Letter("TT"), alphabet.DNA),
+	)
+
+	b := new(bytes.Buffer)
+	w := uc.NewWriter(b)
+
+	assert.NoError(t, w.Write(c))
+	assert.NoError(t, w.Write(d))
+
+	assert.Equal(t,
+		"S\t0\t4\t*\t*\t*\t*\t*\tfoo;size=3\t*\n"+
+			"H\t0\t3\t97.2\t-\t0\t0\t*\tbar;size=1\tfoo;size=3\n"+
+			"C\t0\t2\t*\t*\t*\t*\t*\tfoo;size=3\t*\n"+
+			"S\t1\t2\t*\t*\t*\t*\t*\tbaz;size=1\t*\n"+
+			"C\t1\t1\t*\t*\t*\t*\t*\tbaz;size=1\t*\n",
+		b.String(),
+		"Clusters should be written as S, H and C records.",
+	)
+}
+
+func TestWriteNoMember(t *testing.T) {
+	c := cluster.ParseAnno(
+		linear.NewSeq("foo", []alphabet.Letter("ACGT"), alphabet.DNA),
+	)
+
+	c.Merged = nil
+
+	assert.Error(t, uc.NewWriter(new(bytes.Buffer)).Write(c),
+		"A cluster without member should return an error.",
+	)
+}
+
+func TestWriteError(t *testing.T) {
+	f, err := os.Open(os.DevNull)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	f.Close()
+
+	c := cluster.ParseAnno(
+		linear.NewSeq("foo", []alphabet.Letter("ACGT"), alphabet.DNA),
+	)
+
+	assert.Error(t, uc.NewWriter(f).Write(c),
+		"The error of the writer should be returned.",
+	)
+}
+
+func TestWriteHit(t *testing.T) {
+	b := new(bytes.Buffer)
+	w := uc.NewWriter(b)
+
+	assert.NoError(t, w.WriteHit(uc.Hit{
+		Cluster:   3,
+		Len:       40,
+		Identity:  97.5,
+		Strand:    seq.Minus,
+		Alignment: "9D31M",
+		Query:     "foo",
+		Target:    "bar",
+	}))
+	assert.NoError(t, w.WriteNoHit("baz", 12))
+
+	assert.Equal(t,
+		"H\t3\t40\t97.5\t-\t0\t0\t9D31M\tfoo\tbar\n"+
+			"N\t*\t12\t*\t*\t*\t*\t*\tbaz\t*\n",
+		b.String(),
+		"Hits should be written as H records and queries without hit as N.",
+	)
+}